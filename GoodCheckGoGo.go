@@ -1,1002 +1,1635 @@
-// by Ori
-package main
-
-import (
-	"flag"
-	"fmt"
-	"goodcheckgogo/checklist"
-	"goodcheckgogo/lookup"
-	"goodcheckgogo/options"
-	"goodcheckgogo/requestscurl"
-	"goodcheckgogo/requestsnative"
-	"goodcheckgogo/strategy"
-	"goodcheckgogo/utils"
-	"log"
-	"os"
-	"os/signal"
-	"path/filepath"
-	"sync"
-	"syscall"
-	"time"
-
-	gochoice "github.com/TwiN/go-choice"
-)
-
-const (
-	VERSION     = "0.8.5"
-	PROGRAMNAME = "GoodCheckGoGo"
-
-	CONFIGFILE      = "config.ini"
-	CHECKLISTFOLDER = "Checklists"
-	LOGSFOLDER      = "Logs"
-	STRATEGYFOLDER  = "StrategiesGoGo"
-)
-
-var (
-	allStrategies    []strategy.Strategy
-	allWebsites      []checklist.Website
-	programToUse     options.OptionFoolingProgram
-	testMode         int    = -1 // 1 for native, 2 for curl
-	resolverOfChoice string = ""
-	passes           int    = 0
-	testBegun        bool   = false
-	ggc              string = ""
-	ggcURL           string = ""
-	stratlist        string = ""
-	checklistfile    string = ""
-
-	flagHelp           *bool
-	flagIsQuiet        *bool
-	flagFoolingProgram *string
-	flagMode           *string
-	flagChecklist      *string
-	flagStrategyList   *string
-	flagPasses         *int
-	flagSkipTaskKill   *bool
-	flagSkipSvcKill    *bool
-
-	errInterrupt error = fmt.Errorf("interrupt")
-)
-
-func init() {
-
-	// reading args
-	flagHelp = flag.Bool("?", false, "display help")
-	flagIsQuiet = flag.Bool("q", false, "activates quiet mode")
-	flagFoolingProgram = flag.String("f", "", "fooling program to use; can be either 'gdpi', 'zapret' or 'ciadpi'")
-	flagMode = flag.String("m", "", "mode for requests; can be either 'native' of 'curl'; testing with proxies requires 'curl'")
-	flagChecklist = flag.String("c", "", "checklist filename; must be enclosed in quotes")
-	flagStrategyList = flag.String("s", "", "strategies list filename; must be enclosed in quotes")
-	flagPasses = flag.Int("p", -1, "number of passes; must be greater than 0")
-	flagSkipTaskKill = flag.Bool("skiptaskkill", false, "allow to skip automatic gdpi/zapret/ciadpi tasks termination")
-	flagSkipSvcKill = flag.Bool("skipsvckill", false, "allow to skip automatic gdpi/zapret/ciadpi/windivert services termination (hightly not recommended!)")
-	flag.Parse()
-	if *flagHelp {
-		flag.PrintDefaults()
-		os.Exit(0)
-	}
-
-	// restrarting as admin if not
-	if !utils.AmAdmin(*flagIsQuiet) {
-		err := utils.RunMeElevated(*flagIsQuiet)
-		if err != nil {
-			check(fmt.Errorf("can't elevate privilegies: %v", err))
-		}
-		os.Exit(0)
-	}
-
-	// creating logfile
-	t := time.Now().Format("2006-01-02_15-04-05")
-	n := "logfile_" + PROGRAMNAME + "_" + t + ".log"
-	err := utils.CreateLog(filepath.Join(LOGSFOLDER, n), *flagIsQuiet)
-	if err != nil {
-		check(fmt.Errorf("can't initialize log: %v", err))
-	}
-
-	// writing down some basic info into the log
-	log.Printf("%s v%s\n", PROGRAMNAME, VERSION)
-	log.Printf("\nOS: %s\nArchitecture: %s\n", utils.ReturnWindowsVersion(), utils.ReturnArchitecture())
-	log.Printf("\nCommand-line arguments: %q", os.Args[1:])
-
-	// setting window title
-	err = utils.SetTitle(fmt.Sprintf("%s v%s", PROGRAMNAME, VERSION))
-	if err != nil {
-		check(fmt.Errorf("can't set title: %v", err))
-	}
-
-	// clear console window
-	if !*flagIsQuiet {
-		err = utils.CLS()
-		if err != nil {
-			check(fmt.Errorf("can't clear console window: %v", err))
-		}
-	}
-
-	// parsing config
-	log.Printf("\nReading config...\n")
-	err = options.ParseConfig(CONFIGFILE)
-	if err != nil {
-		check(fmt.Errorf("can't parse config: %v", err))
-	}
-
-	log.Printf("\nInit completed\n")
-}
-
-func main() {
-	var err error
-
-	go func() {
-		sigchan := make(chan os.Signal, 1)
-		signal.Notify(sigchan,
-			os.Interrupt,
-			syscall.SIGTERM, // "the normal way to politely ask a program to terminate"
-			syscall.SIGINT,  // Ctrl+C
-			syscall.SIGQUIT, // Ctrl-\
-			//syscall.SIGKILL, // "always fatal", "SIGKILL and SIGSTOP may not be caught by a program"
-			syscall.SIGHUP, // "terminal is disconnected"
-		)
-		<-sigchan
-		check(errInterrupt)
-	}()
-
-	// program choice
-	log.Printf("\nChoosing fooling program...\n")
-	switch *flagFoolingProgram {
-	case "":
-		programToUse, err = userChooseProgram()
-		if err != nil {
-			check(fmt.Errorf("can't choose fooling program: %v", err))
-		}
-	case "gdpi":
-		log.Printf("Proceeding with '%s' (from args)\n", options.MyOptions.Gdpi.ProgramName)
-		if !options.MyOptions.Gdpi.IsExist {
-			check(fmt.Errorf("flag forcing the use of '%s', but it wasn't found", options.MyOptions.Gdpi.ProgramName))
-		}
-		programToUse = options.MyOptions.Gdpi
-	case "zapret":
-		log.Printf("Proceeding with '%s' (from args)\n", options.MyOptions.Zapret.ProgramName)
-		if !options.MyOptions.Zapret.IsExist {
-			check(fmt.Errorf("flag forcing the use of '%s', but it wasn't found", options.MyOptions.Zapret.ProgramName))
-		}
-		programToUse = options.MyOptions.Zapret
-	case "ciadpi":
-		log.Printf("Proceeding with '%s' (from args)\n", options.MyOptions.Ciadpi.ProgramName)
-		if !options.MyOptions.Ciadpi.IsExist {
-			check(fmt.Errorf("flag forcing the use of '%s', but it wasn't found", options.MyOptions.Ciadpi.ProgramName))
-		}
-		programToUse = options.MyOptions.Ciadpi
-	default:
-		check(fmt.Errorf("flag -f has the wrong value '%s'", *flagFoolingProgram))
-	}
-
-	// stopping programs and services
-	log.Printf("\nStopping active fooling programs and services...\n")
-	err = stopFoolingProgramsAndServices(*flagSkipTaskKill, *flagSkipSvcKill)
-	if err != nil {
-		check(fmt.Errorf("can't stop all fooling programs and services: %v", err))
-	}
-
-	// strategy list choice
-	log.Printf("\nChoosing strategy list...\n")
-	switch *flagStrategyList {
-	case "":
-		stratlist, err = userChooseStrategyList()
-		if err != nil {
-			check(fmt.Errorf("can't choose strategy list: %v", err))
-		}
-	default:
-		stratlist = *flagStrategyList
-		log.Printf("Proceeding with '%s' (from args)\n", *flagStrategyList)
-	}
-
-	// strategy list processing
-	log.Printf("\nParsing strategy list...\n")
-	allStrategies, err = strategy.ReadStrategies(filepath.Join(STRATEGYFOLDER, programToUse.ProgramName, stratlist))
-	if err != nil {
-		check(fmt.Errorf("can't parse strategy list: %v", err))
-	}
-	if programToUse.WorksAsProxy && strategy.Proxy == "noproxy" {
-		check(fmt.Errorf("choosen program works as proxy, but proxy itself is unset"))
-	}
-
-	// test mode choice
-	log.Printf("\nChoosing requests mode...\n")
-	switch *flagMode {
-	case "":
-		testMode, err = userChooseTestMode()
-		if err != nil {
-			check(fmt.Errorf("can't choose requests mode: %v", err))
-		}
-	case "native":
-		testMode = 1
-		log.Println("Proceeding with 'Native' (from args)")
-		if strategy.Proxy != "noproxy" {
-			check(fmt.Errorf("testing with proxy requires 'Curl', but flag is forcing 'Native' mode"))
-		}
-	case "curl":
-		testMode = 2
-		log.Println("Proceeding with 'Curl' (from args)")
-	default:
-		check(fmt.Errorf("flag -m has the wrong value '%s'", *flagMode))
-	}
-
-	// connectivity check
-	if options.MyOptions.NetConnTest.Value {
-		//log.Printf("\nChecking '%s' connectivity...\n", strategy.ProtoFull)
-		log.Printf("\nChecking '%s' connectivity...\n", fmt.Sprintf("tcp%d", strategy.IPV))
-		switch testMode {
-		case 1:
-			// native
-			requestsnative.SetTransport(5, 2)
-			err = requestsnative.CheckConnectivityNative()
-			if err == nil {
-				break
-			}
-			if options.MyOptions.SkipCertVerify.Value {
-				check(fmt.Errorf("connectivity test failed: %v", err))
-			}
-			options.MyOptions.SkipCertVerify.Value = true
-			requestsnative.SetTransport(5, 2)
-			log.Println("Normal connectivity test failed, switching mode to insecure")
-			err = requestsnative.CheckConnectivityNative()
-			if err != nil {
-				check(fmt.Errorf("connectivity test failed: %v", err))
-			}
-			if !*flagIsQuiet {
-				err := userChooseContinueInsecure()
-				if err != nil {
-					check(fmt.Errorf("can't choose whether to continue or not: %v", err))
-				}
-			} else {
-				log.Println("Auto-accept continue insecure in quiet mode")
-			}
-		case 2:
-			// curl
-			c, err := requestscurl.CheckConnectivityCurl()
-			if err != nil {
-				check(fmt.Errorf("connectivity test failed: %v", err))
-			}
-			if c {
-				break
-			}
-			if options.MyOptions.SkipCertVerify.Value {
-				check(fmt.Errorf("connectivity test failed"))
-			}
-			options.MyOptions.SkipCertVerify.Value = true
-			log.Println("Normal connectivity test failed, switching mode to insecure")
-			c, err = requestscurl.CheckConnectivityCurl()
-			if err != nil {
-				check(fmt.Errorf("connectivity test failed: %v", err))
-			}
-			if !c {
-				check(fmt.Errorf("connectivity test failed"))
-			}
-			if !*flagIsQuiet {
-				err := userChooseContinueInsecure()
-				if err != nil {
-					check(fmt.Errorf("can't choose whether to continue or not: %v", err))
-				}
-			} else {
-				log.Println("Auto-accept continue insecure in quiet mode")
-			}
-		default:
-			check(fmt.Errorf("schrodinger's cat: 'testMode' value is out of bounds: '%d'", testMode))
-		}
-	} else {
-		log.Printf("\nSkipping connectivity test...\n")
-	}
-
-	// resolver connectivity test
-	if options.MyOptions.UseDoH.Value && strategy.Proxy == "noproxy" {
-		domainOnly := utils.InsensitiveReplace(options.MyOptions.NetConnTestURL.Value, "https://", "")
-		switch testMode {
-		case 1:
-			// native
-			log.Printf("\nChecking DNS resolvers availability (Native)...\n")
-			for _, resolver := range options.MyOptions.DoHResolvers.Value {
-				log.Printf("Testing '%s' resolver, looking up ipv%d for '%s'...\n", resolver, strategy.IPV, domainOnly)
-				dnsResult, err := lookup.DnsLookup(resolver, domainOnly, strategy.IPV, options.MyOptions.ResolverNativeTimeout.Value, options.MyOptions.ResolverNativeRetries.Value, options.MyOptions.SkipCertVerify.Value)
-				if err != nil || !dnsResult.Response {
-					log.Println("No proper response from DNS, trying next one...")
-					continue
-				}
-				if dnsResult.Zero {
-					log.Println("Zero response from DNS, trying next one...")
-					continue
-				}
-				var _ip string
-				for _, answer := range dnsResult.Answer {
-					if strategy.IPV == 4 && answer.A != "" {
-						_ip = answer.A
-						break
-					} else if strategy.IPV == 6 && answer.AAAA != "" {
-						_ip = answer.AAAA
-						break
-					}
-				}
-				if _ip == "" {
-					log.Println("No valid IP was found, trying next one...")
-					continue
-				}
-				resolverOfChoice = resolver
-				log.Printf("Resolver seems ok: '%s' -> %s\n", domainOnly, _ip)
-				break
-			}
-			if resolverOfChoice == "" {
-				check(fmt.Errorf("all resolvers failed"))
-			}
-		case 2:
-			// curl
-			log.Printf("\nChecking DNS resolvers availability (Curl)...\n")
-			for _, resolver := range options.MyOptions.DoHResolvers.Value {
-				log.Printf("Testing '%s' resolver, looking up ipv%d for '%s'...\n", resolver, strategy.IPV, domainOnly)
-				dnsResult := requestscurl.DnsLookupCurl(resolver, domainOnly)
-				if dnsResult == "" {
-					log.Println("Can't resolve IP, trying next one...")
-					continue
-				}
-				resolverOfChoice = resolver
-				log.Printf("Resolver seems ok: '%s' -> %s\n", domainOnly, dnsResult)
-				break
-			}
-			if resolverOfChoice == "" {
-				check(fmt.Errorf("all resolvers failed"))
-			}
-		}
-	} else {
-		log.Printf("\nCustom resolver disabled or proxy in effect, skipping DNS availability test...\n")
-	}
-
-	// checklist choice
-	log.Printf("\nChoosing checklist...\n")
-	switch *flagChecklist {
-	case "":
-		checklistfile, err = userChooseChecklist()
-		if err != nil {
-			check(fmt.Errorf("can't choose checklist: %v", err))
-		}
-	default:
-		checklistfile = *flagChecklist
-		log.Printf("Proceeding with '%s' (from args)\n", *flagChecklist)
-	}
-
-	// checklist processing
-	log.Printf("\nReading checklist...\n")
-	allWebsites, err = checklist.ReadChecklist(filepath.Join(CHECKLISTFOLDER, checklistfile))
-	if err != nil {
-		check(fmt.Errorf("can't read checklist: %v", err))
-	}
-
-	// auto GGC
-	if options.MyOptions.AutoGGC.Value {
-		log.Printf("\nLooking for Google Cache Server URL...\n")
-		switch testMode {
-		case 1:
-			//native
-			for _, url := range options.MyOptions.MappingURLs.Value {
-				ggc = requestsnative.ExtractClusterNative(url)
-				if ggc != "" {
-					break
-				}
-			}
-			if ggc != "" {
-				ggcURL = checklist.ConvertClusterToURL(ggc)
-				log.Println("------------------")
-				log.Println("Your googlevideo cluster:", ggc)
-				log.Println("Your googlevideo URL:", ggcURL)
-				log.Println("------------------")
-				allWebsites = append(allWebsites, checklist.NewWebsite(ggcURL))
-			} else {
-				log.Println("Can't find googlevideo cluster")
-			}
-		case 2:
-			//curl
-			for _, url := range options.MyOptions.MappingURLs.Value {
-				ggc = requestscurl.ExtractClusterCurl(url)
-				if ggc != "" {
-					break
-				}
-			}
-			if ggc != "" {
-				ggcURL = checklist.ConvertClusterToURL(ggc)
-				log.Println("------------------")
-				log.Println("Your googlevideo cluster:", ggc)
-				log.Println("Your googlevideo URL:", ggcURL)
-				log.Println("------------------")
-				allWebsites = append(allWebsites, checklist.NewWebsite(ggcURL))
-			} else {
-				log.Println("Can't find googlevideo cluster")
-			}
-		}
-	} else {
-		log.Printf("\nAuto-looking for Google Cache Server disabled, skipping...\n")
-	}
-	if len(allWebsites) == 0 {
-		check(fmt.Errorf("no URLs to check"))
-	}
-
-	// resolving
-	if strategy.Proxy == "noproxy" {
-		log.Printf("\nResolving IP addresses...\n")
-		switch testMode {
-		case 1:
-			//native
-			for i := 0; i < len(allWebsites); i++ {
-				err = utils.SetTitle(fmt.Sprintf("%s v%s - Resolving %d/%d", PROGRAMNAME, VERSION, (i + 1), len(allWebsites)))
-				if err != nil {
-					check(fmt.Errorf("can't set title: %v", err))
-				}
-
-				domainOnly := utils.InsensitiveReplace(allWebsites[i].Address, "https://", "")
-				dnsResult, err := lookup.DnsLookup(resolverOfChoice, domainOnly, strategy.IPV, options.MyOptions.ResolverNativeTimeout.Value, options.MyOptions.ResolverNativeRetries.Value, options.MyOptions.SkipCertVerify.Value)
-				if err != nil {
-					check(fmt.Errorf("can't finish DNS lookup: %v", err))
-				}
-				if !dnsResult.Response {
-					log.Printf("No response from DNS for '%s'; removing URL from the checklist...\n", domainOnly)
-					continue
-				}
-				if dnsResult.Zero {
-					log.Printf("No valid IPv%d was found for '%s'; removing URL from the checklist...\n", strategy.IPV, domainOnly)
-					continue
-				}
-				if strategy.IPV == 4 {
-					var _ip string
-					for _, answer := range dnsResult.Answer {
-						if answer.A != "" {
-							_ip = answer.A
-							break
-						}
-					}
-					allWebsites[i].IP = _ip
-				} else {
-					var _ip string
-					for _, answer := range dnsResult.Answer {
-						if answer.AAAA != "" {
-							_ip = answer.AAAA
-							break
-						}
-					}
-					allWebsites[i].IP = _ip
-				}
-				if allWebsites[i].IP != "" {
-					allWebsites[i].IsResolved = true
-					log.Printf("IPv%d for '%s' was found: %s", strategy.IPV, domainOnly, allWebsites[i].IP)
-				} else {
-					log.Printf("No valid IPv%d was found for '%s'; removing URL from the checklist...\n", strategy.IPV, domainOnly)
-				}
-			}
-		case 2:
-			//curl
-			for i := 0; i < len(allWebsites); i++ {
-				domainOnly := utils.InsensitiveReplace(allWebsites[i].Address, "https://", "")
-				dnsResult := requestscurl.DnsLookupCurl(resolverOfChoice, domainOnly)
-				if dnsResult == "" {
-					log.Printf("No valid IPv%d was found for '%s'; removing URL from the checklist...\n", strategy.IPV, domainOnly)
-					continue
-				}
-				allWebsites[i].IsResolved = true
-				allWebsites[i].IP = dnsResult
-				log.Printf("IPv%d for '%s' was found: %s", strategy.IPV, domainOnly, allWebsites[i].IP)
-			}
-		}
-		var w []checklist.Website
-		for _, site := range allWebsites {
-			if site.IsResolved {
-				w = append(w, site)
-			}
-		}
-		n := len(allWebsites)
-		allWebsites = w
-		n = n - len(allWebsites)
-		log.Printf("URLs removed: %d\nURLs left: %d\n", n, len(allWebsites))
-		if len(allWebsites) == 0 {
-			check(fmt.Errorf("no URLs left"))
-		}
-		err = utils.SetTitle(fmt.Sprintf("%s v%s", PROGRAMNAME, VERSION))
-		if err != nil {
-			check(fmt.Errorf("can't set title: %v", err))
-		}
-	}
-
-	// passes choice
-	log.Printf("\nChoosing number of passes...\n")
-	if *flagPasses <= 0 {
-		passes, err = userChoosePasses()
-		if err != nil {
-			check(fmt.Errorf("can't choose number of passes: %v", err))
-		}
-	} else {
-		passes = *flagPasses
-		log.Printf("Proceeding with '%d' pass(es) (from args)\n", *flagPasses)
-	}
-
-	// time estimation etc
-	log.Printf("\nTime estimation...\n")
-	if !*flagIsQuiet {
-		err = utils.CLS()
-		if err != nil {
-			check(fmt.Errorf("can't clear console window: %v", err))
-		}
-	}
-	log.Println("Program:", programToUse.ProgramName)
-	if testMode == 1 {
-		log.Println("Requests mode: Native")
-	} else {
-		log.Println("Requests mode: Curl")
-	}
-	log.Println("Protocol:", strategy.Protocol)
-	log.Println("IP version:", strategy.IPV)
-	log.Println("Proxy:", strategy.Proxy)
-	log.Println("Strategies list:", stratlist)
-	log.Println("Total strategies:", len(allStrategies))
-	log.Println("Checklist:", checklistfile)
-	log.Println("Total URLs:", len(allWebsites))
-	log.Println("Number of passes:", passes)
-	log.Println("Timeout:", options.MyOptions.ConnTimeout.Value, "sec")
-	estimStepMilliseconds := options.MyOptions.ConnTimeout.Value*1000 + options.MyOptions.InternalTimeoutMs.Value*2 + 100
-	estimTMilliseconds := len(allStrategies) * passes * estimStepMilliseconds
-	estimT := utils.ConvertMillisecondsSecondsToMinutesSeconds(estimTMilliseconds)
-	log.Println("\nEstimated time for a test:", estimT)
-	if !*flagIsQuiet {
-		fmt.Println("\nPreparations complete, press [ENTER] to begin...")
-		fmt.Scanln()
-	}
-
-	// main loop
-	startT := time.Now()
-	log.Printf("\nTesting started at %s...\n", startT.String())
-	totalStrategies := len(allStrategies)
-	totalURLs := len(allWebsites)
-
-	if testMode == 1 {
-		//requestsnative.SetThreads(len(allWebsites))
-		requestsnative.SetTransport(len(allWebsites)*2, options.MyOptions.ConnTimeout.Value)
-	}
-	var keysCurl []string
-	if testMode == 2 {
-		keysCurl = requestscurl.FormRequestsKeys(resolverOfChoice, allWebsites)
-		log.Println("Curl request line formed:", keysCurl)
-	}
-
-	// if testMode == 1 && strategy.Proxy != "noproxy" {
-	// 	requestsnative.SetProxy()
-	// }
-
-	if !*flagIsQuiet {
-		err = utils.CLS()
-		if err != nil {
-			check(fmt.Errorf("can't clear console window: %v", err))
-		}
-	}
-
-	testBegun = true
-
-	for i := 0; i < totalStrategies; i++ {
-		log.Printf("\nLaunching '%s', strategy %d/%d: %s\n", programToUse.ProgramName, (i + 1), totalStrategies, allStrategies[i].Keys)
-		prog, err := utils.StartProgramWithArguments(programToUse.ExecutableFullPath, allStrategies[i].Keys)
-		if err != nil {
-			check(fmt.Errorf("can't launch fooling program with arguments: %v", err))
-		}
-		for j := 1; j <= passes; j++ {
-			utils.SetTitle(fmt.Sprintf("%s v%s - Testing - Strategy %d/%d, Pass %d/%d - Time left: %s", PROGRAMNAME, VERSION, (i + 1), totalStrategies, j, passes, estimT))
-			estimTMilliseconds = estimTMilliseconds - estimStepMilliseconds
-			estimT = utils.ConvertMillisecondsSecondsToMinutesSeconds(estimTMilliseconds)
-
-			log.Printf("Making requests, pass %d/%d...\n", j, passes)
-			time.Sleep(time.Duration(options.MyOptions.InternalTimeoutMs.Value) * time.Millisecond)
-			switch testMode {
-			case 1:
-				// native
-				wg := sync.WaitGroup{}
-				for p := 0; p < totalURLs; p++ {
-					wg.Add(1)
-					go requestsnative.SendRequest(&wg, &allWebsites[p], allWebsites)
-				}
-				wg.Wait()
-				requestsnative.CloseIdle()
-			case 2:
-				//curl
-				err = requestscurl.SendRequestsAndParse(keysCurl, &allWebsites)
-				if err != nil {
-					check(fmt.Errorf("can't finish curl requests: %v", err))
-				}
-			}
-
-			log.Printf("Displaying results...\n")
-			totalS := 0
-			for n := 0; n < len(allWebsites); n++ {
-				var s string
-				if allWebsites[n].LastResponseCode == 0 {
-					s = "[CODE: 000] FAILURE"
-				} else if allWebsites[n].LastResponseCode == -1 {
-					s = "[CODE: ERR] ERROR  "
-				} else {
-					totalS++
-					s = fmt.Sprintf("[CODE: %d] SUCCESS", allWebsites[n].LastResponseCode)
-				}
-				log.Printf("%s\t%s\n", s, allWebsites[n].Address)
-				//allWebsites[n].LastResponseCode = -1
-			}
-			log.Printf("Successes: %d/%d\n", totalS, totalURLs)
-			if !allStrategies[i].IsTested || allStrategies[i].Successes > totalS {
-				allStrategies[i].Successes = totalS
-				allStrategies[i].IsTested = true
-				log.Printf("Writing it down; worst result for this strategy: %d/%d\n\n", allStrategies[i].Successes, totalURLs)
-			} else {
-				log.Printf("Skipping it; worst result for this strategy: %d/%d\n\n", allStrategies[i].Successes, totalURLs)
-			}
-		}
-		if allStrategies[i].Successes > 0 {
-			allStrategies[i].HasSuccesses = true
-			for k := 0; k < totalURLs; k++ {
-				if allWebsites[k].LastResponseCode != -1 && allWebsites[k].LastResponseCode != 0 && allWebsites[k].MostSuccessfulStrategySuccesses < allStrategies[i].Successes {
-					allWebsites[k].MostSuccessfulStrategySuccesses = allStrategies[i].Successes
-					allWebsites[k].MostSuccessfulStrategyNum = i
-				}
-			}
-		} else {
-			log.Println("This strategy has no successes")
-		}
-		// for k := 0; k < totalURLs; k++ {
-		// 	allWebsites[k].LastResponseCode = -1
-		// }
-		log.Printf("Terminating program...\n")
-		err = utils.StopProgram(prog)
-		if err != nil {
-			check(fmt.Errorf("can't terminate fooling program: %v", err))
-		}
-		time.Sleep(time.Duration(options.MyOptions.InternalTimeoutMs.Value) * time.Millisecond)
-	}
-
-	utils.SetTitle(fmt.Sprintf("%s v%s - Test completed", PROGRAMNAME, VERSION))
-	log.Printf("\nTest ended at %s\n", time.Now().String())
-	log.Printf("Total time taken: %s\n", time.Since(startT))
-
-	// stopping programs and services
-	log.Printf("\nStopping still active fooling program and WinDivert services...\n")
-	err = stopFoolingProgramsAndServicesMini(*flagSkipTaskKill, *flagSkipSvcKill)
-	if err != nil {
-		check(fmt.Errorf("can't stop all fooling programs and services: %v", err))
-	}
-
-	// final results showcase
-	log.Printf("\nDisplaying summary...\n")
-	finalResultsShowcase()
-
-	log.Printf("\nAll Done\n")
-	if !*flagIsQuiet {
-		fmt.Printf("\nPress [ENTER] to exit...\n")
-		fmt.Scanln()
-	}
-	os.Exit(0)
-}
-
-func stopFoolingProgramsAndServices(skiptaskkill bool, skipsvckill bool) error {
-	if !skiptaskkill {
-		err := utils.TaskKill(options.MyOptions.Gdpi.ExecutableName, options.MyOptions.Zapret.ExecutableName, options.MyOptions.Ciadpi.ExecutableName)
-		if err != nil {
-			return fmt.Errorf("can't properly terminate fooling programs: %v", err)
-		}
-	}
-	if !skipsvckill {
-		var snames []string
-		snames = append(snames, options.MyOptions.Gdpi.ServiceNames...)
-		snames = append(snames, options.MyOptions.Zapret.ServiceNames...)
-		snames = append(snames, options.MyOptions.Ciadpi.ServiceNames...)
-		snames = append(snames, options.MyOptions.WinDivert.Value...)
-		err := utils.StopAndDeleteServices(snames...)
-		if err != nil {
-			return fmt.Errorf("can't properly stop fooling services: %v", err)
-		}
-	}
-	return nil
-}
-
-func stopFoolingProgramsAndServicesMini(skiptaskkill bool, skipsvckill bool) error {
-	if !skiptaskkill {
-		err := utils.TaskKill(programToUse.ExecutableName)
-		if err != nil {
-			return fmt.Errorf("can't properly terminate fooling programs: %v", err)
-		}
-	}
-	if !skipsvckill {
-		var snames []string
-		snames = append(snames, options.MyOptions.WinDivert.Value...)
-		err := utils.StopAndDeleteServices(snames...)
-		if err != nil {
-			return fmt.Errorf("can't properly stop fooling services: %v", err)
-		}
-	}
-	return nil
-}
-
-func finalResultsShowcase() {
-	if !testBegun {
-		return
-	}
-	totalURLs := len(allWebsites)
-	if totalURLs == 0 {
-		return
-	}
-	totalStrategies := len(allStrategies)
-	if totalStrategies == 0 {
-		return
-	}
-	log.Printf("\n--------------------RESULTS BY URL---------------------\n")
-	var urlsNoSuccess []int
-	for i := 0; i < totalURLs; i++ {
-		if !allWebsites[i].HasSuccesses {
-			urlsNoSuccess = append(urlsNoSuccess, i)
-		}
-	}
-	if len(urlsNoSuccess) > 0 {
-		log.Println("\nURLs with NO successes:")
-		for i := 0; i < len(urlsNoSuccess); i++ {
-			log.Printf("%s | IP: %s\n", allWebsites[urlsNoSuccess[i]].Address, allWebsites[urlsNoSuccess[i]].IP)
-		}
-	}
-	if len(urlsNoSuccess) != totalURLs {
-		log.Println("\nURLs with successes:")
-		for i := 0; i < totalURLs; i++ {
-			if allWebsites[i].HasSuccesses {
-				log.Printf("%s | IP: %s | Best strategy: %s", allWebsites[i].Address, allWebsites[i].IP, allStrategies[allWebsites[i].MostSuccessfulStrategyNum].Keys)
-			}
-		}
-	}
-	log.Printf("\n------------------RESULTS BY STRATEGY------------------\n")
-	for i := 0; i <= totalURLs; i++ {
-		var lines []strategy.Strategy
-		for _, strat := range allStrategies {
-			if strat.Successes == i {
-				lines = append(lines, strat)
-			}
-		}
-		if len(lines) > 0 {
-			log.Printf("\nStrategies with %d/%d successes:\n", i, totalURLs)
-			for _, line := range lines {
-				log.Println(line.Keys)
-			}
-		}
-	}
-	log.Printf("\n----------------------INFORMATION----------------------\n\n")
-	log.Println("Program:", programToUse.ProgramName)
-	mode := "Native"
-	if testMode == 2 {
-		mode = "Curl"
-	}
-	log.Println("Requests mode:", mode)
-	log.Println("Protocol:", strategy.Protocol)
-	log.Println("IP version:", strategy.IPV)
-	if strategy.Proxy != "noproxy" {
-		log.Println("Proxy:", strategy.Proxy)
-	}
-	log.Println("Strategies list:", stratlist)
-	log.Println("Checklist:", checklistfile)
-	log.Println("Number of passes:", passes)
-	log.Println("Timeout:", options.MyOptions.ConnTimeout.Value, "sec")
-	if resolverOfChoice != "" {
-		log.Println("Resolver:", resolverOfChoice)
-	} else {
-		log.Println("Resolver: System")
-	}
-	if options.MyOptions.AutoGGC.Value && ggcURL != "" {
-		log.Println("Google Video Cluster:", ggc)
-		log.Println("Google Video URL:", ggcURL)
-	}
-	testBegun = false
-}
-
-func userChooseContinueInsecure() error {
-	_, _choiceN, err := gochoice.Pick(
-		fmt.Sprintln("\nATTENTION: Normal connectivity test failed, but insecure connectivity test succeeded\nEither root certificates are corrupted or your firewall/antivirus software is interfering\n\nContinue anyway? Test results may be compromised if your ISP performs a certificate substitution:"),
-		[]string{"Continue in insecure mode", "Exit"},
-	)
-	if err != nil {
-		return fmt.Errorf("problem with a gochoice: %v", err)
-	}
-	if _choiceN == 1 {
-		exitByChoice()
-	}
-	log.Println("Continuing in insecure mode by user's choice")
-	return nil
-}
-
-func userChooseProgram() (options.OptionFoolingProgram, error) {
-	var programList []string
-	if options.MyOptions.Gdpi.IsExist {
-		programList = append(programList, options.MyOptions.Gdpi.ProgramName)
-	}
-	if options.MyOptions.Zapret.IsExist {
-		programList = append(programList, options.MyOptions.Zapret.ProgramName)
-	}
-	if options.MyOptions.Ciadpi.IsExist {
-		programList = append(programList, options.MyOptions.Ciadpi.ProgramName)
-	}
-	programList = append(programList, "Exit")
-
-	choice, _, err := gochoice.Pick(
-		fmt.Sprintf("\nATTENTION: '%s' '%s' and '%s' will be closed, their services will be removed\n\nChoose the fooling program to use:", options.MyOptions.Gdpi.ProgramName, options.MyOptions.Zapret.ProgramName, options.MyOptions.Ciadpi.ProgramName),
-		programList,
-	)
-	if err != nil {
-		var p options.OptionFoolingProgram
-		return p, fmt.Errorf("problem with a gochoice: %v", err)
-	}
-	switch choice {
-	case options.MyOptions.Gdpi.ProgramName:
-		log.Printf("Proceeding with '%s'\n", choice)
-		return options.MyOptions.Gdpi, nil
-	case options.MyOptions.Zapret.ProgramName:
-		log.Printf("Proceeding with '%s'\n", choice)
-		return options.MyOptions.Zapret, nil
-	case options.MyOptions.Ciadpi.ProgramName:
-		log.Printf("Proceeding with '%s'\n", choice)
-		return options.MyOptions.Ciadpi, nil
-	case "Exit":
-		exitByChoice()
-	}
-	var p options.OptionFoolingProgram
-	return p, fmt.Errorf("schrodinger's cat: choice out of bounds: '%s'", choice)
-}
-
-func userChooseTestMode() (int, error) {
-	var modes []string
-	if strategy.Proxy != "noproxy" {
-		modes = []string{"Use Curl (only this mode is available when proxy is in use)"}
-	} else {
-		modes = []string{"Use Native (faster)", "Use Curl (reliable)"}
-	}
-	modes = append(modes, "Exit")
-	choice, _, err := gochoice.Pick(
-		"\nChoose requests mode:",
-		modes,
-	)
-	if err != nil {
-		return 0, fmt.Errorf("problem with a gochoice: %v", err)
-	}
-	switch choice {
-	case "Exit":
-		exitByChoice()
-	case "Use Native (faster)":
-		log.Println("Proceeding with 'Native'")
-		return 1, nil
-	case "Use Curl (reliable)":
-		log.Println("Proceeding with 'Curl'")
-		return 2, nil
-	case "Use Curl (only this mode is available when proxy is in use)":
-		log.Println("Proceeding with 'Curl' (forced by the use of proxy)")
-		return 2, nil
-	}
-	return 0, fmt.Errorf("schrodinger's cat: choice out of bounds: '%s'", choice)
-}
-
-func userChoosePasses() (int, error) {
-	var passesVariants = []string{"1", "2", "3", "4", "5", "6", "7", "8", "9"}
-
-	passesVariants = append(passesVariants, "Exit")
-	choice, choiceN, err := gochoice.Pick(
-		"\nNumber of passes:",
-		passesVariants,
-	)
-	if err != nil {
-		return 0, fmt.Errorf("problem with a gochoice: %v", err)
-	}
-	if choice == "Exit" {
-		exitByChoice()
-	}
-	log.Printf("Proceeding with '%d' pass(es)\n", (choiceN + 1))
-	return (choiceN + 1), nil
-}
-
-func userChooseChecklist() (string, error) {
-	log.Printf("Reading folder '%s'...\n", CHECKLISTFOLDER)
-	entries, err := os.ReadDir(CHECKLISTFOLDER)
-	if err != nil {
-		return "", fmt.Errorf("can't read folder '%s': %v", CHECKLISTFOLDER, err)
-	}
-
-	var checklistsInFolder []string
-	for _, e := range entries {
-		if !e.IsDir() {
-			checklistsInFolder = append(checklistsInFolder, e.Name())
-		}
-	}
-	if len(checklistsInFolder) == 0 {
-		return "", fmt.Errorf("can't find any checklists in folder '%s'", CHECKLISTFOLDER)
-	}
-
-	checklistsInFolder = append(checklistsInFolder, "Exit")
-	choice, _, err := gochoice.Pick(
-		"\nChoose the checklist:",
-		checklistsInFolder,
-	)
-	if err != nil {
-		return "", fmt.Errorf("problem with a gochoice: %v", err)
-	}
-	if choice == "Exit" {
-		exitByChoice()
-	}
-	log.Printf("Proceeding with '%s'\n", choice)
-
-	return choice, nil
-}
-
-func userChooseStrategyList() (string, error) {
-	fullpath := filepath.Join(STRATEGYFOLDER, programToUse.ProgramName)
-	log.Printf("Reading folder '%s'...\n", fullpath)
-	entries, err := os.ReadDir(fullpath)
-	if err != nil {
-		return "", fmt.Errorf("can't read folder '%s': %v", fullpath, err)
-	}
-
-	var strategiesListsInFolder []string
-	for _, e := range entries {
-		if !e.IsDir() {
-			strategiesListsInFolder = append(strategiesListsInFolder, e.Name())
-		}
-	}
-	if len(strategiesListsInFolder) == 0 {
-		return "", fmt.Errorf("can't find any strategy lists in folder '%s'", fullpath)
-	}
-
-	strategiesListsInFolder = append(strategiesListsInFolder, "Exit")
-	choice, _, err := gochoice.Pick(
-		"\nChoose the strategy list:",
-		strategiesListsInFolder,
-	)
-	if err != nil {
-		return "", fmt.Errorf("problem with a gochoice: %v", err)
-	}
-	if choice == "Exit" {
-		exitByChoice()
-	}
-	log.Printf("Proceeding with '%s'\n", choice)
-
-	return choice, nil
-}
-
-func exitByChoice() {
-	log.Printf("\nExiting by users choice...\n")
-	os.Exit(0)
-}
-
-func check(err error) {
-	switch err {
-	case nil:
-		return
-
-	case errInterrupt:
-		log.Printf("\nSignal catched: %v\n", err)
-
-		if testBegun {
-			// stopping programs and services
-			log.Printf("\nTest interrupted, stopping still active fooling programs and services, further errors will be silently ignored at this point...\n")
-			stopFoolingProgramsAndServicesMini(*flagSkipTaskKill, *flagSkipSvcKill)
-
-			finalResultsShowcase()
-		}
-
-		log.Printf("\nExiting with an interrupt...\n")
-		os.Exit(1)
-
-	default:
-		log.Printf("\nCritical error: %v\n", err)
-
-		if testBegun {
-			// stopping programs and services
-			log.Printf("\nTest failed, stopping still active fooling programs and services, further errors will be silently ignored at this point...\n")
-			stopFoolingProgramsAndServicesMini(*flagSkipTaskKill, *flagSkipSvcKill)
-
-			finalResultsShowcase()
-		} else {
-			if !*flagIsQuiet {
-				fmt.Printf("\nPress [ENTER] to exit...\n")
-				fmt.Scanln()
-			}
-		}
-
-		log.Printf("\nExiting with an error...\n")
-		os.Exit(1)
-	}
-}
+// by Ori
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"goodcheckgogo/blockpage"
+	"goodcheckgogo/certcheck"
+	"goodcheckgogo/checklist"
+	"goodcheckgogo/lookup"
+	"goodcheckgogo/options"
+	"goodcheckgogo/requestscurl"
+	"goodcheckgogo/requestsnative"
+	"goodcheckgogo/strategy"
+	"goodcheckgogo/supervisor"
+	"goodcheckgogo/tlsprofile"
+	"goodcheckgogo/utils"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	gochoice "github.com/TwiN/go-choice"
+)
+
+const (
+	VERSION     = "0.8.5"
+	PROGRAMNAME = "GoodCheckGoGo"
+
+	CONFIGFILE      = "config.ini"
+	CHECKLISTFOLDER = "Checklists"
+	LOGSFOLDER      = "Logs"
+	STRATEGYFOLDER  = "StrategiesGoGo"
+	RECOVERYFILE    = "recovery.json"
+)
+
+var (
+	allStrategies    []strategy.Strategy
+	allWebsites      []checklist.Website
+	programToUse     options.OptionFoolingProgram
+	testMode         int    = -1 // 1 for native, 2 for curl
+	resolverOfChoice string = ""
+	passes           int    = 0
+	testBegun        bool   = false
+	ggc              string = ""
+	ggcURL           string = ""
+	stratlist        string = ""
+	checklistfile    string = ""
+	localDNSStarted  bool   = false
+
+	// invalid entries from config, kept when the ranking is written back
+	skippedResolvers []string
+
+	flagHelp           *bool
+	flagIsQuiet        *bool
+	flagFoolingProgram *string
+	flagMode           *string
+	flagChecklist      *string
+	flagStrategyList   *string
+	flagPasses         *int
+	flagSkipTaskKill   *bool
+	flagSkipSvcKill    *bool
+	flagDNSDiagnosis   *bool
+	flagResolvers      *bool
+	flagRefreshDNS     *bool
+	flagLocalDNS       *bool
+	flagProbe          *string
+	flagTLSProfile     *string
+
+	errInterrupt error = fmt.Errorf("interrupt")
+
+	// cancelled on interrupt; requests, curl and fooling program stop with it
+	runCtx, cancelRun = context.WithCancel(context.Background())
+	// while set, main loop handles interrupts by itself
+	testRunning atomic.Bool
+	// main and interrupt handler may both reach the summary, it's shown by whichever comes first
+	showcaseOnce sync.Once
+)
+
+func init() {
+
+	// reading args
+	flagHelp = flag.Bool("?", false, "display help")
+	flagIsQuiet = flag.Bool("q", false, "activates quiet mode")
+	flagFoolingProgram = flag.String("f", "", "fooling program to use; can be either 'gdpi', 'zapret' or 'ciadpi'")
+	flagMode = flag.String("m", "", "mode for requests; can be either 'native' of 'curl'; native proxy testing supports socks5, socks5h and http over TCP")
+	flagChecklist = flag.String("c", "", "checklist filename; must be enclosed in quotes")
+	flagStrategyList = flag.String("s", "", "strategies list filename; must be enclosed in quotes")
+	flagPasses = flag.Int("p", -1, "number of passes; must be greater than 0")
+	flagSkipTaskKill = flag.Bool("skiptaskkill", false, "allow to skip automatic gdpi/zapret/ciadpi tasks termination")
+	flagSkipSvcKill = flag.Bool("skipsvckill", false, "allow to skip automatic gdpi/zapret/ciadpi/windivert services termination (hightly not recommended!)")
+	flagDNSDiagnosis = flag.Bool("dnsdiag", false, "compare system resolver answers with the chosen DoH resolver to find DNS-blocked URLs")
+	flagResolvers = flag.Bool("resolvers", false, "benchmark all configured resolvers against the checklist and use the best one")
+	flagRefreshDNS = flag.Bool("refreshdns", false, "ignore cached DNS answers and resolve everything again")
+	flagLocalDNS = flag.Bool("localdns", false, "run a local DNS forwarding to the chosen resolver for the whole test")
+	flagTLSProfile = flag.String("tls", "", "TLS profile for requests; built-in: 'default', 'tls12', 'tls13', 'minimal', 'browser', 'large'; more can be added in config")
+	flagProbe = flag.String("probe", "", "probe mode; can be either 'status' (status line is enough), 'volume' (body must arrive as well) or 'handshake' (TLS or QUIC handshake only, native mode)")
+	flag.Parse()
+	if *flagHelp {
+		flag.PrintDefaults()
+		os.Exit(0)
+	}
+
+	// restrarting as admin if not
+	if !utils.AmAdmin(*flagIsQuiet) {
+		err := utils.RunMeElevated(*flagIsQuiet)
+		if err != nil {
+			check(fmt.Errorf("can't elevate privilegies: %v", err))
+		}
+		os.Exit(0)
+	}
+
+	// creating logfile
+	t := time.Now().Format("2006-01-02_15-04-05")
+	n := "logfile_" + PROGRAMNAME + "_" + t + ".log"
+	err := utils.CreateLog(filepath.Join(LOGSFOLDER, n), *flagIsQuiet)
+	if err != nil {
+		check(fmt.Errorf("can't initialize log: %v", err))
+	}
+
+	// writing down some basic info into the log
+	log.Printf("%s v%s\n", PROGRAMNAME, VERSION)
+	log.Printf("\nOS: %s\nArchitecture: %s\n", utils.ReturnWindowsVersion(), utils.ReturnArchitecture())
+	log.Printf("\nCommand-line arguments: %q", os.Args[1:])
+
+	// setting window title
+	err = utils.SetTitle(fmt.Sprintf("%s v%s", PROGRAMNAME, VERSION))
+	if err != nil {
+		check(fmt.Errorf("can't set title: %v", err))
+	}
+
+	// clear console window
+	if !*flagIsQuiet {
+		err = utils.CLS()
+		if err != nil {
+			check(fmt.Errorf("can't clear console window: %v", err))
+		}
+	}
+
+	// parsing config
+	log.Printf("\nReading config...\n")
+	err = options.ParseConfig(CONFIGFILE)
+	if err != nil {
+		check(fmt.Errorf("can't parse config: %v", err))
+	}
+	err = validateResolvers()
+	if err != nil {
+		check(fmt.Errorf("can't validate resolvers: %v", err))
+	}
+	if *flagTLSProfile != "" {
+		options.MyOptions.TLSProfile.Value = *flagTLSProfile
+	}
+	if *flagProbe != "" {
+		err = options.SetProbeMode(*flagProbe)
+		if err != nil {
+			check(fmt.Errorf("flag -probe has the wrong value: %v", err))
+		}
+	}
+
+	log.Printf("\nInit completed\n")
+}
+
+func main() {
+	var err error
+	defer supervisor.Guard()
+
+	go func() {
+		defer supervisor.Guard()
+		sigchan := make(chan os.Signal, 1)
+		signal.Notify(sigchan,
+			os.Interrupt,
+			syscall.SIGTERM, // "the normal way to politely ask a program to terminate"
+			syscall.SIGINT,  // Ctrl+C
+			syscall.SIGQUIT, // Ctrl-\
+			//syscall.SIGKILL, // "always fatal", "SIGKILL and SIGSTOP may not be caught by a program"
+			syscall.SIGHUP, // "terminal is disconnected"
+		)
+		<-sigchan
+		cancelRun()
+		if !testRunning.Load() {
+			go check(errInterrupt)
+		}
+		// cleanup may hang on a stuck program or service; whatever is left is cleaned up on the next launch
+		<-sigchan
+		log.Printf("\nSecond signal catched, exiting without waiting for cleanup...\n")
+		os.Exit(1)
+	}()
+
+	// recovery after a crash
+	supervisor.Init(RECOVERYFILE)
+	err = supervisor.Recover()
+	if err != nil {
+		check(fmt.Errorf("can't clean up after previous run: %v", err))
+	}
+
+	// program choice
+	log.Printf("\nChoosing fooling program...\n")
+	switch *flagFoolingProgram {
+	case "":
+		programToUse, err = userChooseProgram()
+		if err != nil {
+			check(fmt.Errorf("can't choose fooling program: %v", err))
+		}
+	case "gdpi":
+		log.Printf("Proceeding with '%s' (from args)\n", options.MyOptions.Gdpi.ProgramName)
+		if !options.MyOptions.Gdpi.IsExist {
+			check(fmt.Errorf("flag forcing the use of '%s', but it wasn't found", options.MyOptions.Gdpi.ProgramName))
+		}
+		programToUse = options.MyOptions.Gdpi
+	case "zapret":
+		log.Printf("Proceeding with '%s' (from args)\n", options.MyOptions.Zapret.ProgramName)
+		if !options.MyOptions.Zapret.IsExist {
+			check(fmt.Errorf("flag forcing the use of '%s', but it wasn't found", options.MyOptions.Zapret.ProgramName))
+		}
+		programToUse = options.MyOptions.Zapret
+	case "ciadpi":
+		log.Printf("Proceeding with '%s' (from args)\n", options.MyOptions.Ciadpi.ProgramName)
+		if !options.MyOptions.Ciadpi.IsExist {
+			check(fmt.Errorf("flag forcing the use of '%s', but it wasn't found", options.MyOptions.Ciadpi.ProgramName))
+		}
+		programToUse = options.MyOptions.Ciadpi
+	default:
+		check(fmt.Errorf("flag -f has the wrong value '%s'", *flagFoolingProgram))
+	}
+
+	// stopping programs and services
+	log.Printf("\nStopping active fooling programs and services...\n")
+	err = stopFoolingProgramsAndServices(*flagSkipTaskKill, *flagSkipSvcKill)
+	if err != nil {
+		check(fmt.Errorf("can't stop all fooling programs and services: %v", err))
+	}
+
+	// strategy list choice
+	log.Printf("\nChoosing strategy list...\n")
+	switch *flagStrategyList {
+	case "":
+		stratlist, err = userChooseStrategyList()
+		if err != nil {
+			check(fmt.Errorf("can't choose strategy list: %v", err))
+		}
+	default:
+		stratlist = *flagStrategyList
+		log.Printf("Proceeding with '%s' (from args)\n", *flagStrategyList)
+	}
+
+	// strategy list processing
+	log.Printf("\nParsing strategy list...\n")
+	allStrategies, err = strategy.ReadStrategies(filepath.Join(STRATEGYFOLDER, programToUse.ProgramName, stratlist))
+	if err != nil {
+		check(fmt.Errorf("can't parse strategy list: %v", err))
+	}
+	if programToUse.WorksAsProxy && strategy.Proxy == "noproxy" {
+		check(fmt.Errorf("choosen program works as proxy, but proxy itself is unset"))
+	}
+
+	// test mode choice
+	log.Printf("\nChoosing requests mode...\n")
+	switch *flagMode {
+	case "":
+		testMode, err = userChooseTestMode()
+		if err != nil {
+			check(fmt.Errorf("can't choose requests mode: %v", err))
+		}
+	case "native":
+		testMode = 1
+		log.Println("Proceeding with 'Native' (from args)")
+		if strategy.Proxy != "noproxy" {
+			err = nativeProxySupported()
+			if err != nil {
+				check(fmt.Errorf("flag is forcing 'Native' mode, but proxy can't be used with it: %v", err))
+			}
+		}
+	case "curl":
+		testMode = 2
+		log.Println("Proceeding with 'Curl' (from args)")
+	default:
+		check(fmt.Errorf("flag -m has the wrong value '%s'", *flagMode))
+	}
+	if testMode == 2 && !options.MyOptions.Curl.IsExist {
+		check(fmt.Errorf("'Curl' mode is chosen, but '%s' wasn't found", options.MyOptions.Curl.ProgramName))
+	}
+	if testMode == 2 && options.MyOptions.ProbeMode.Value == "handshake" {
+		check(fmt.Errorf("handshake probe is available in 'Native' mode only"))
+	}
+
+	// connectivity check
+	if options.MyOptions.NetConnTest.Value {
+		//log.Printf("\nChecking '%s' connectivity...\n", strategy.ProtoFull)
+		for _, ipv := range strategy.IPVs {
+			log.Printf("\nChecking '%s' connectivity...\n", fmt.Sprintf("tcp%d", ipv))
+			switch testMode {
+			case 1:
+				// native
+				requestsnative.SetTransport(5, 2)
+				err = requestsnative.CheckConnectivityNative(ipv)
+				if err == nil {
+					break
+				}
+				if options.MyOptions.SkipCertVerify.Value {
+					check(fmt.Errorf("connectivity test failed: %v", err))
+				}
+				options.MyOptions.SkipCertVerify.Value = true
+				requestsnative.SetTransport(5, 2)
+				log.Println("Normal connectivity test failed, switching mode to insecure")
+				log.Println("Certificates will still be checked against the system pool; substituted ones are reported as 'mitm'")
+				err = requestsnative.CheckConnectivityNative(ipv)
+				if err != nil {
+					check(fmt.Errorf("connectivity test failed: %v", err))
+				}
+				if !*flagIsQuiet {
+					err := userChooseContinueInsecure()
+					if err != nil {
+						check(fmt.Errorf("can't choose whether to continue or not: %v", err))
+					}
+				} else {
+					log.Println("Auto-accept continue insecure in quiet mode")
+				}
+			case 2:
+				// curl
+				c, err := requestscurl.CheckConnectivityCurl(ipv)
+				if err != nil {
+					check(fmt.Errorf("connectivity test failed: %v", err))
+				}
+				if c {
+					break
+				}
+				if options.MyOptions.SkipCertVerify.Value {
+					check(fmt.Errorf("connectivity test failed"))
+				}
+				options.MyOptions.SkipCertVerify.Value = true
+				log.Println("Normal connectivity test failed, switching mode to insecure")
+				c, err = requestscurl.CheckConnectivityCurl(ipv)
+				if err != nil {
+					check(fmt.Errorf("connectivity test failed: %v", err))
+				}
+				if !c {
+					check(fmt.Errorf("connectivity test failed"))
+				}
+				if !*flagIsQuiet {
+					err := userChooseContinueInsecure()
+					if err != nil {
+						check(fmt.Errorf("can't choose whether to continue or not: %v", err))
+					}
+				} else {
+					log.Println("Auto-accept continue insecure in quiet mode")
+				}
+			default:
+				check(fmt.Errorf("schrodinger's cat: 'testMode' value is out of bounds: '%d'", testMode))
+			}
+		}
+	} else {
+		log.Printf("\nSkipping connectivity test...\n")
+	}
+
+	// DNS cache
+	if options.MyOptions.UseDNSCache.Value {
+		log.Printf("\nLoading DNS cache from '%s'...\n", options.MyOptions.DNSCacheFile.Value)
+		err = lookup.OpenCache(options.MyOptions.DNSCacheFile.Value, options.MyOptions.DNSCacheMaxStaleness.Value, *flagRefreshDNS)
+		if err != nil {
+			log.Printf("Can't load DNS cache, starting with an empty one: %v\n", err)
+		}
+		if *flagRefreshDNS {
+			log.Println("Cached answers will be refreshed (from args)")
+		}
+	}
+
+	// resolver connectivity test
+	if options.MyOptions.UseDoH.Value && strategy.Proxy == "noproxy" {
+		domainOnly := utils.InsensitiveReplace(options.MyOptions.NetConnTestURL.Value, "https://", "")
+		// both modes resolve natively and pass addresses to curl, so cache and bootstrap apply to curl too
+		log.Printf("\nChecking DNS resolvers availability...\n")
+		for _, resolver := range options.MyOptions.Resolvers.Value {
+			// with several IP versions the resolver has to answer for each of them
+			ok := true
+			for _, ipv := range strategy.IPVs {
+				log.Printf("Testing '%s' resolver, looking up ipv%d for '%s'...\n", lookup.DescribeResolver(resolver), ipv, domainOnly)
+				if !checkResolver(resolver, domainOnly, ipv) {
+					ok = false
+					break
+				}
+			}
+			if !ok {
+				continue
+			}
+			resolverOfChoice = resolver
+			break
+		}
+		if resolverOfChoice == "" {
+			check(fmt.Errorf("all resolvers failed"))
+		}
+	} else {
+		log.Printf("\nCustom resolver disabled or proxy in effect, skipping DNS availability test...\n")
+	}
+
+	// checklist choice
+	log.Printf("\nChoosing checklist...\n")
+	switch *flagChecklist {
+	case "":
+		checklistfile, err = userChooseChecklist()
+		if err != nil {
+			check(fmt.Errorf("can't choose checklist: %v", err))
+		}
+	default:
+		checklistfile = *flagChecklist
+		log.Printf("Proceeding with '%s' (from args)\n", *flagChecklist)
+	}
+
+	// checklist processing
+	log.Printf("\nReading checklist...\n")
+	allWebsites, err = checklist.ReadChecklist(filepath.Join(CHECKLISTFOLDER, checklistfile))
+	if err != nil {
+		check(fmt.Errorf("can't read checklist: %v", err))
+	}
+
+	// TLS profiles
+	log.Printf("\nChecking TLS profiles...\n")
+	// every custom profile, used or not, so a broken one in config doesn't wait for the day it's picked
+	for name := range options.MyOptions.TLSProfiles {
+		_, err = tlsprofile.Lookup(name)
+		if err != nil {
+			check(err)
+		}
+	}
+	_, err = tlsprofile.Lookup(options.MyOptions.TLSProfile.Value)
+	if err != nil {
+		check(fmt.Errorf("can't use TLS profile: %v", err))
+	}
+	for _, site := range allWebsites {
+		if site.TLSProfile == "" {
+			continue
+		}
+		_, err = tlsprofile.Lookup(site.TLSProfile)
+		if err != nil {
+			check(fmt.Errorf("can't use TLS profile for '%s': %v", site.Address, err))
+		}
+	}
+
+	// block-page fingerprints
+	log.Printf("\nReading block-page fingerprints...\n")
+	err = blockpage.Load(options.MyOptions.BlockpageFile.Value)
+	if err != nil {
+		check(fmt.Errorf("can't read block-page fingerprints: %v", err))
+	}
+
+	// certificate pins
+	log.Printf("\nReading certificate pins...\n")
+	err = certcheck.Load(options.MyOptions.CertPinsFile.Value)
+	if err != nil {
+		check(fmt.Errorf("can't read certificate pins: %v", err))
+	}
+	if testMode == 2 {
+		log.Println("Certificate chains are recorded in 'Native' mode only, 'Curl' mode relies on its own verification")
+		if options.MyOptions.SkipCertVerify.Value {
+			log.Println("WARNING: 'Curl' mode is insecure, substituted certificates are caught only if curl's TLS backend still reports failed verification")
+		}
+	}
+
+	// auto GGC
+	if options.MyOptions.AutoGGC.Value {
+		log.Printf("\nLooking for Google Cache Server URL...\n")
+		switch testMode {
+		case 1:
+			//native
+			for _, url := range options.MyOptions.MappingURLs.Value {
+				ggc = requestsnative.ExtractClusterNative(url)
+				if ggc != "" {
+					break
+				}
+			}
+			if ggc != "" {
+				ggcURL = checklist.ConvertClusterToURL(ggc)
+				log.Println("------------------")
+				log.Println("Your googlevideo cluster:", ggc)
+				log.Println("Your googlevideo URL:", ggcURL)
+				log.Println("------------------")
+				allWebsites = append(allWebsites, checklist.NewWebsite(ggcURL))
+			} else {
+				log.Println("Can't find googlevideo cluster")
+			}
+		case 2:
+			//curl
+			for _, url := range options.MyOptions.MappingURLs.Value {
+				ggc = requestscurl.ExtractClusterCurl(url)
+				if ggc != "" {
+					break
+				}
+			}
+			if ggc != "" {
+				ggcURL = checklist.ConvertClusterToURL(ggc)
+				log.Println("------------------")
+				log.Println("Your googlevideo cluster:", ggc)
+				log.Println("Your googlevideo URL:", ggcURL)
+				log.Println("------------------")
+				allWebsites = append(allWebsites, checklist.NewWebsite(ggcURL))
+			} else {
+				log.Println("Can't find googlevideo cluster")
+			}
+		}
+	} else {
+		log.Printf("\nAuto-looking for Google Cache Server disabled, skipping...\n")
+	}
+	if len(allWebsites) == 0 {
+		check(fmt.Errorf("no URLs to check"))
+	}
+
+	// plain HTTP
+	plainHTTP := 0
+	for i := range allWebsites {
+		if strategy.Protocol == "HTTP" {
+			allWebsites[i].PlainHTTP = true
+		}
+		if !allWebsites[i].PlainHTTP {
+			continue
+		}
+		if strategy.Protocol == "UDP" {
+			check(fmt.Errorf("'%s' is set to be checked over HTTP, but strategy list is for UDP", allWebsites[i].Address))
+		}
+		plainHTTP++
+	}
+	if plainHTTP > 0 {
+		log.Printf("\nURLs to check over plain HTTP (port 80): %d/%d\n", plainHTTP, len(allWebsites))
+		if options.MyOptions.ProbeMode.Value == "handshake" {
+			check(fmt.Errorf("handshake probe can't be used for plain HTTP"))
+		}
+	}
+
+	// IP versions
+	allWebsites = checklist.ForEachIPV(allWebsites, strategy.IPVs)
+	if strategy.IsDualStack() {
+		log.Printf("\nTesting IP versions %v separately, URLs to check: %d\n", strategy.IPVs, len(allWebsites))
+	}
+
+	// resolvers benchmark
+	if (options.MyOptions.ResolverBenchmark.Value || *flagResolvers) && options.MyOptions.UseDoH.Value && strategy.Proxy == "noproxy" {
+		log.Printf("\nBenchmarking %d resolvers against %d URLs...\n", len(options.MyOptions.Resolvers.Value), len(allWebsites))
+		err = utils.SetTitle(fmt.Sprintf("%s v%s - Benchmarking resolvers", PROGRAMNAME, VERSION))
+		if err != nil {
+			check(fmt.Errorf("can't set title: %v", err))
+		}
+		// sites come once per IP version already, so every family is benchmarked
+		var hosts []string
+		var ipvs []int
+		for _, site := range allWebsites {
+			hosts = append(hosts, utils.InsensitiveReplace(site.Address, "https://", ""))
+			ipvs = append(ipvs, site.IPV)
+		}
+		scores := lookup.BenchmarkResolvers(runCtx, options.MyOptions.Resolvers.Value, hosts, ipvs, options.MyOptions.ResolverNativeTimeout.Value, options.MyOptions.ResolverNativeRetries.Value, options.MyOptions.SkipCertVerify.Value, options.MyOptions.ResolverConcurrency.Value)
+		var ranking []string
+		for n, score := range scores {
+			log.Printf("%d. %s | %s | failures: %d/%d, empty: %d | consistency: %.0f%% | latency: median %s, avg %s\n", (n + 1), score.Resolver, score.Transport, score.Failures, score.Queries, score.Empty, lookup.Consistency(score)*100, score.MedianLatency.Round(time.Millisecond), score.AvgLatency.Round(time.Millisecond))
+			ranking = append(ranking, score.Resolver)
+		}
+		if len(scores) > 0 && lookup.FailureRate(scores[0]) < 1 {
+			resolverOfChoice = scores[0].Resolver
+			log.Printf("Proceeding with the best resolver: '%s'\n", lookup.DescribeResolver(resolverOfChoice))
+		} else {
+			log.Println("No resolver answered, keeping the one found earlier")
+		}
+		if options.MyOptions.ResolverBenchmarkWriteConfig.Value {
+			err = options.WriteResolvers(ranking, skippedResolvers)
+			if err != nil {
+				check(fmt.Errorf("can't save resolvers ranking: %v", err))
+			}
+		}
+		err = utils.SetTitle(fmt.Sprintf("%s v%s", PROGRAMNAME, VERSION))
+		if err != nil {
+			check(fmt.Errorf("can't set title: %v", err))
+		}
+	}
+
+	// DNS diagnosis
+	if options.MyOptions.DNSDiagnosis.Value || *flagDNSDiagnosis {
+		if resolverOfChoice != "" {
+			log.Printf("\nLooking for DNS tampering, comparing system resolver with '%s'...\n", lookup.DescribeResolver(resolverOfChoice))
+			dnsBlocked := 0
+			stubIPs := append(slices.Clone(options.MyOptions.DNSStubIPs.Value), blockpage.IPs()...)
+			for i := 0; i < len(allWebsites); i++ {
+				err = utils.SetTitle(fmt.Sprintf("%s v%s - DNS diagnosis %d/%d", PROGRAMNAME, VERSION, (i + 1), len(allWebsites)))
+				if err != nil {
+					check(fmt.Errorf("can't set title: %v", err))
+				}
+				domainOnly := utils.InsensitiveReplace(allWebsites[i].Address, "https://", "")
+				d := lookup.DiagnoseHost(runCtx, resolverOfChoice, domainOnly, allWebsites[i].IPV, options.MyOptions.ResolverNativeTimeout.Value, options.MyOptions.ResolverNativeRetries.Value, options.MyOptions.SkipCertVerify.Value, stubIPs)
+				allWebsites[i].DNSVerdict = d.Verdict
+				log.Printf("[DNS: %s] %s | System: %s %s %s | Resolver: %s %s %s\n", d.Verdict, siteName(allWebsites[i]), d.SystemRcode, d.SystemIPs, d.SystemASNs, d.ResolverRcode, d.ResolverIPs, d.ResolverASNs)
+				if lookup.IsDNSBlocked(d.Verdict) {
+					dnsBlocked++
+				}
+			}
+			log.Printf("URLs blocked by DNS: %d/%d\n", dnsBlocked, len(allWebsites))
+			err = utils.SetTitle(fmt.Sprintf("%s v%s", PROGRAMNAME, VERSION))
+			if err != nil {
+				check(fmt.Errorf("can't set title: %v", err))
+			}
+		} else {
+			log.Printf("\nDNS diagnosis requires a custom resolver to compare with, skipping...\n")
+		}
+	}
+
+	// resolving
+	if strategy.Proxy == "noproxy" {
+		log.Printf("\nResolving IP addresses...\n")
+		for i := 0; i < len(allWebsites); i++ {
+			if !allWebsites[i].IsPinned {
+				continue
+			}
+			domainOnly := utils.InsensitiveReplace(allWebsites[i].Address, "https://", "")
+			checklist.SetTargets(&allWebsites[i], checklist.PinnedIPsForVersion(allWebsites[i], allWebsites[i].IPV), 0)
+			if !allWebsites[i].IsResolved {
+				log.Printf("No pinned IPv%d for '%s'; removing URL from the checklist...\n", allWebsites[i].IPV, domainOnly)
+				continue
+			}
+			log.Printf("IPv%d for '%s' is pinned, skipping lookup: %s\n", allWebsites[i].IPV, domainOnly, checklist.ListIPs(allWebsites[i]))
+		}
+		var toResolve []int
+		for i := 0; i < len(allWebsites); i++ {
+			if !allWebsites[i].IsPinned {
+				toResolve = append(toResolve, i)
+			}
+		}
+		resolveLogs := make([][]string, len(allWebsites))
+		resolvedN := 0
+		var titleMutex sync.Mutex
+		utils.RunWorkerPool(options.MyOptions.ResolverConcurrency.Value, len(toResolve), func(job int) {
+			defer supervisor.Guard()
+			i := toResolve[job]
+			resolveLogs[i] = resolveWebsite(&allWebsites[i])
+			titleMutex.Lock()
+			resolvedN++
+			utils.SetTitle(fmt.Sprintf("%s v%s - Resolving %d/%d", PROGRAMNAME, VERSION, resolvedN, len(toResolve)))
+			titleMutex.Unlock()
+		})
+		lookup.CloseUpstreams()
+		err = lookup.SaveCache()
+		if err != nil {
+			log.Printf("Can't save DNS cache: %v\n", err)
+		}
+		for i := 0; i < len(allWebsites); i++ {
+			for _, line := range resolveLogs[i] {
+				log.Println(line)
+			}
+		}
+		var w []checklist.Website
+		for _, site := range allWebsites {
+			if site.IsResolved {
+				w = append(w, site)
+			}
+		}
+		n := len(allWebsites)
+		allWebsites = w
+		n = n - len(allWebsites)
+		log.Printf("URLs removed: %d\nURLs left: %d\n", n, len(allWebsites))
+		if len(allWebsites) == 0 {
+			check(fmt.Errorf("no URLs left"))
+		}
+		err = utils.SetTitle(fmt.Sprintf("%s v%s", PROGRAMNAME, VERSION))
+		if err != nil {
+			check(fmt.Errorf("can't set title: %v", err))
+		}
+	} else {
+		// proxy resolves addresses by itself, so every URL is tested once without a fixed IP
+		for i := 0; i < len(allWebsites); i++ {
+			checklist.SetTargets(&allWebsites[i], []string{""}, 0)
+		}
+	}
+
+	// HTTPS records
+	if options.MyOptions.HTTPSRecords.Value {
+		log.Printf("\nLooking up HTTPS records...\n")
+		httpsLogs := make([]string, len(allWebsites))
+		httpsN := 0
+		var titleMutex sync.Mutex
+		utils.RunWorkerPool(options.MyOptions.ResolverConcurrency.Value, len(allWebsites), func(i int) {
+			defer supervisor.Guard()
+			httpsLogs[i] = lookupHTTPSRecords(&allWebsites[i])
+			titleMutex.Lock()
+			httpsN++
+			utils.SetTitle(fmt.Sprintf("%s v%s - HTTPS records %d/%d", PROGRAMNAME, VERSION, httpsN, len(allWebsites)))
+			titleMutex.Unlock()
+		})
+		lookup.CloseUpstreams()
+		withECH, withH3 := 0, 0
+		for i := 0; i < len(allWebsites); i++ {
+			log.Println(httpsLogs[i])
+			if allWebsites[i].AdvertisesECH {
+				withECH++
+			}
+			if allWebsites[i].AdvertisesH3 {
+				withH3++
+			}
+		}
+		log.Printf("URLs advertising ECH: %d/%d\nURLs advertising HTTP/3: %d/%d\n", withECH, len(allWebsites), withH3, len(allWebsites))
+		err = utils.SetTitle(fmt.Sprintf("%s v%s", PROGRAMNAME, VERSION))
+		if err != nil {
+			check(fmt.Errorf("can't set title: %v", err))
+		}
+	}
+
+	// local DNS
+	if options.MyOptions.LocalDNS.Value || *flagLocalDNS {
+		log.Printf("\nStarting local DNS at '%s'...\n", options.MyOptions.LocalDNSListen.Value)
+		err = lookup.StartStub(options.MyOptions.LocalDNSListen.Value, resolverOfChoice, options.MyOptions.ResolverNativeTimeout.Value, options.MyOptions.SkipCertVerify.Value)
+		if err != nil {
+			check(fmt.Errorf("can't start local DNS: %v", err))
+		}
+		localDNSStarted = true
+		log.Printf("Local DNS is forwarding to '%s'\n", lookup.DescribeResolver(resolverOfChoice))
+		// fooling programs and browsers use system DNS, so it's pointed to the local one for the whole run
+		host, port, _ := net.SplitHostPort(options.MyOptions.LocalDNSListen.Value)
+		if port != "53" || net.ParseIP(host).To4() == nil {
+			log.Printf("System DNS can only use an IPv4 address on port 53, point it to '%s' manually\n", options.MyOptions.LocalDNSListen.Value)
+		} else {
+			adapters, err := utils.GetAdapterDNS()
+			if err != nil {
+				check(fmt.Errorf("can't read system DNS: %v", err))
+			}
+			err = supervisor.RegisterDNS(adapters)
+			if err != nil {
+				check(fmt.Errorf("can't register system DNS change: %v", err))
+			}
+			err = utils.SetAdapterDNS(adapters, host)
+			if err != nil {
+				check(fmt.Errorf("can't point system DNS to local DNS: %v", err))
+			}
+			log.Printf("System DNS of %d adapter(s) points to '%s' until the end of the run\n", len(adapters), host)
+		}
+	}
+
+	// passes choice
+	log.Printf("\nChoosing number of passes...\n")
+	if *flagPasses <= 0 {
+		passes, err = userChoosePasses()
+		if err != nil {
+			check(fmt.Errorf("can't choose number of passes: %v", err))
+		}
+	} else {
+		passes = *flagPasses
+		log.Printf("Proceeding with '%d' pass(es) (from args)\n", *flagPasses)
+	}
+
+	// time estimation etc
+	log.Printf("\nTime estimation...\n")
+	if !*flagIsQuiet {
+		err = utils.CLS()
+		if err != nil {
+			check(fmt.Errorf("can't clear console window: %v", err))
+		}
+	}
+	log.Println("Program:", programToUse.ProgramName)
+	if testMode == 1 {
+		log.Println("Requests mode: Native")
+	} else {
+		log.Println("Requests mode: Curl")
+	}
+	log.Println("Protocol:", strategy.Protocol)
+	log.Println("IP version:", formatIPVs())
+	log.Println("Proxy:", strategy.Proxy)
+	log.Println("Strategies list:", stratlist)
+	log.Println("Total strategies:", len(allStrategies))
+	log.Println("Checklist:", checklistfile)
+	log.Println("Total URLs:", len(allWebsites))
+	log.Println("Number of passes:", passes)
+	log.Println("Timeout:", options.MyOptions.ConnTimeout.Value, "sec")
+	if testMode == 1 {
+		log.Println("Redirect policy:", options.MyOptions.RedirectPolicy.Value)
+	}
+	log.Println("TLS profile:", options.MyOptions.TLSProfile.Value)
+	if options.MyOptions.ProbeMode.Value == "volume" {
+		log.Println("Probe: volume,", options.MyOptions.VolumeBytes.Value, "bytes")
+	} else {
+		log.Println("Probe:", options.MyOptions.ProbeMode.Value)
+	}
+	if resolverOfChoice != "" {
+		log.Println("Resolver:", lookup.DescribeResolver(resolverOfChoice))
+	}
+	if options.MyOptions.MaxIPsPerSite.Value != 1 {
+		log.Printf("IPs required to succeed: %d%%\n", options.MyOptions.IPSuccessThreshold.Value)
+	}
+	estimStepMilliseconds := options.MyOptions.ConnTimeout.Value*1000 + options.MyOptions.InternalTimeoutMs.Value*2 + 100
+	estimTMilliseconds := len(allStrategies) * passes * estimStepMilliseconds
+	estimT := utils.ConvertMillisecondsSecondsToMinutesSeconds(estimTMilliseconds)
+	log.Println("\nEstimated time for a test:", estimT)
+	if !*flagIsQuiet {
+		fmt.Println("\nPreparations complete, press [ENTER] to begin...")
+		fmt.Scanln()
+	}
+
+	// main loop
+	startT := time.Now()
+	log.Printf("\nTesting started at %s...\n", startT.String())
+	totalStrategies := len(allStrategies)
+	totalURLs := len(allWebsites)
+	urlsByIPV := countByIPV()
+
+	if testMode == 1 {
+		//requestsnative.SetThreads(len(allWebsites))
+		requestsnative.SetTransport(len(allWebsites)*2, options.MyOptions.ConnTimeout.Value)
+		err = requestsnative.SetRoutes(allWebsites)
+		if err != nil {
+			check(fmt.Errorf("can't set routes: %v", err))
+		}
+	}
+	var keysCurl []string
+	if testMode == 2 {
+		err = requestscurl.SetWriteOut()
+		if err != nil {
+			check(fmt.Errorf("can't choose curl results format: %v", err))
+		}
+		keysCurl, err = requestscurl.FormRequestsKeys(resolverOfChoice, allWebsites)
+		if err != nil {
+			check(fmt.Errorf("can't form curl requests: %v", err))
+		}
+		log.Println("Curl arguments formed:", keysCurl)
+	}
+
+	if testMode == 1 && strategy.Proxy != "noproxy" {
+		log.Println("\nSetting up proxy for native mode...")
+		err = requestsnative.SetProxy(strategy.Proxy)
+		if err != nil {
+			check(fmt.Errorf("can't set proxy: %v", err))
+		}
+	}
+
+	if !*flagIsQuiet {
+		err = utils.CLS()
+		if err != nil {
+			check(fmt.Errorf("can't clear console window: %v", err))
+		}
+	}
+
+	if !programToUse.WorksAsProxy && !*flagSkipSvcKill {
+		err = supervisor.RegisterServices(options.MyOptions.WinDivert.Value...)
+		if err != nil {
+			check(fmt.Errorf("can't register fooling services: %v", err))
+		}
+	}
+
+	testBegun = true
+	testRunning.Store(true)
+
+	for i := 0; i < totalStrategies; i++ {
+		if runCtx.Err() != nil {
+			check(errInterrupt)
+		}
+		lookup.SetStubTag(fmt.Sprintf("strategy %d", (i + 1)))
+		log.Printf("\nLaunching '%s', strategy %d/%d: %s\n", programToUse.ProgramName, (i + 1), totalStrategies, allStrategies[i].Keys)
+		prog, err := utils.StartProgramWithArguments(runCtx, programToUse.ExecutableFullPath, allStrategies[i].Keys)
+		if err != nil && runCtx.Err() != nil {
+			// start failed because the run was cancelled under it
+			check(errInterrupt)
+		}
+		if err != nil {
+			check(fmt.Errorf("can't launch fooling program with arguments: %v", err))
+		}
+		err = supervisor.Register(programToUse.ExecutableName, prog)
+		if err != nil {
+			check(fmt.Errorf("can't register fooling program: %v", err))
+		}
+		for j := 1; j <= passes; j++ {
+			utils.SetTitle(fmt.Sprintf("%s v%s - Testing - Strategy %d/%d, Pass %d/%d - Time left: %s", PROGRAMNAME, VERSION, (i + 1), totalStrategies, j, passes, estimT))
+			estimTMilliseconds = estimTMilliseconds - estimStepMilliseconds
+			estimT = utils.ConvertMillisecondsSecondsToMinutesSeconds(estimTMilliseconds)
+
+			log.Printf("Making requests, pass %d/%d...\n", j, passes)
+			time.Sleep(time.Duration(options.MyOptions.InternalTimeoutMs.Value) * time.Millisecond)
+			switch testMode {
+			case 1:
+				// native
+				wg := sync.WaitGroup{}
+				for p := 0; p < totalURLs; p++ {
+					for t := 0; t < len(allWebsites[p].Targets); t++ {
+						wg.Add(1)
+						go requestsnative.SendRequest(runCtx, &wg, &allWebsites[p], &allWebsites[p].Targets[t])
+					}
+				}
+				wg.Wait()
+				requestsnative.CloseIdle()
+			case 2:
+				//curl
+				err = requestscurl.SendRequestsAndParse(runCtx, keysCurl, &allWebsites)
+				if err != nil && runCtx.Err() == nil {
+					check(fmt.Errorf("can't finish curl requests: %v", err))
+				}
+			}
+			if runCtx.Err() != nil {
+				// results of an interrupted pass are incomplete, so they aren't counted
+				check(errInterrupt)
+			}
+
+			log.Printf("Displaying results...\n")
+			totalS := 0
+			successesByIPV := map[int]int{}
+			for n := 0; n < len(allWebsites); n++ {
+				succeededIPs := checklist.SummarizeTargets(&allWebsites[n], options.MyOptions.IPSuccessThreshold.Value)
+				var s string
+				if allWebsites[n].LastResponseCode == 0 {
+					s = "[CODE: 000] FAILURE"
+				} else if allWebsites[n].LastResponseCode == -1 {
+					s = "[CODE: ERR] ERROR  "
+				} else {
+					totalS++
+					successesByIPV[allWebsites[n].IPV]++
+					s = fmt.Sprintf("[CODE: %d] SUCCESS", allWebsites[n].LastResponseCode)
+					if options.MyOptions.ProbeMode.Value == "handshake" {
+						s = "[HANDSHAKE] SUCCESS"
+					}
+				}
+				if len(allWebsites[n].Targets) > 1 {
+					log.Printf("%s\t%s (%d/%d IPs)\n", s, siteName(allWebsites[n]), succeededIPs, len(allWebsites[n].Targets))
+					for _, target := range allWebsites[n].Targets {
+						log.Printf("\t[CODE: %03d] %s%s%s%s%s%s\n", target.LastResponseCode, target.IP, formatHandshake(target), formatVolume(target), formatOutcome(target), formatCert(target), formatRedirects(target))
+					}
+				} else {
+					log.Printf("%s\t%s%s%s%s%s%s\n", s, siteName(allWebsites[n]), formatHandshake(allWebsites[n].Targets[0]), formatVolume(allWebsites[n].Targets[0]), formatOutcome(allWebsites[n].Targets[0]), formatCert(allWebsites[n].Targets[0]), formatRedirects(allWebsites[n].Targets[0]))
+				}
+				//allWebsites[n].LastResponseCode = -1
+			}
+			log.Printf("Successes: %d/%d\n", totalS, totalURLs)
+			if strategy.IsDualStack() {
+				for _, ipv := range strategy.IPVs {
+					log.Printf("IPv%d successes: %d/%d\n", ipv, successesByIPV[ipv], urlsByIPV[ipv])
+				}
+			}
+			for _, ipv := range strategy.IPVs {
+				if worst, ok := allStrategies[i].SuccessesByIPV[ipv]; !ok || worst > successesByIPV[ipv] {
+					allStrategies[i].SuccessesByIPV[ipv] = successesByIPV[ipv]
+				}
+			}
+			if !allStrategies[i].IsTested || allStrategies[i].Successes > totalS {
+				allStrategies[i].Successes = totalS
+				allStrategies[i].IsTested = true
+				log.Printf("Writing it down; worst result for this strategy: %d/%d\n\n", allStrategies[i].Successes, totalURLs)
+			} else {
+				log.Printf("Skipping it; worst result for this strategy: %d/%d\n\n", allStrategies[i].Successes, totalURLs)
+			}
+		}
+		if allStrategies[i].Successes > 0 {
+			allStrategies[i].HasSuccesses = true
+			for k := 0; k < totalURLs; k++ {
+				// best strategy is picked by the results of the site's own IP version
+				if allWebsites[k].LastResponseCode != -1 && allWebsites[k].LastResponseCode != 0 && allWebsites[k].MostSuccessfulStrategySuccesses < allStrategies[i].SuccessesByIPV[allWebsites[k].IPV] {
+					allWebsites[k].MostSuccessfulStrategySuccesses = allStrategies[i].SuccessesByIPV[allWebsites[k].IPV]
+					allWebsites[k].MostSuccessfulStrategyNum = i
+				}
+			}
+		} else {
+			log.Println("This strategy has no successes")
+		}
+		// for k := 0; k < totalURLs; k++ {
+		// 	allWebsites[k].LastResponseCode = -1
+		// }
+		if localDNSStarted {
+			logStubQueries(fmt.Sprintf("strategy %d", (i + 1)))
+		}
+		log.Printf("Terminating program...\n")
+		err = utils.StopProgram(prog)
+		if err != nil {
+			check(fmt.Errorf("can't terminate fooling program: %v", err))
+		}
+		supervisor.Unregister(prog)
+		time.Sleep(time.Duration(options.MyOptions.InternalTimeoutMs.Value) * time.Millisecond)
+	}
+
+	testRunning.Store(false)
+	utils.SetTitle(fmt.Sprintf("%s v%s - Test completed", PROGRAMNAME, VERSION))
+	log.Printf("\nTest ended at %s\n", time.Now().String())
+	log.Printf("Total time taken: %s\n", time.Since(startT))
+
+	// stopping programs and services
+	log.Printf("\nStopping still active fooling program and WinDivert services...\n")
+	err = stopFoolingProgramsAndServicesMini(*flagSkipTaskKill, *flagSkipSvcKill)
+	if err != nil {
+		check(fmt.Errorf("can't stop all fooling programs and services: %v", err))
+	}
+	supervisor.Teardown()
+
+	if localDNSStarted {
+		err = lookup.StopStub()
+		if err != nil {
+			log.Printf("Can't properly stop local DNS: %v\n", err)
+		}
+	}
+
+	// final results showcase
+	log.Printf("\nDisplaying summary...\n")
+	showcaseOnce.Do(finalResultsShowcase)
+
+	log.Printf("\nAll Done\n")
+	if !*flagIsQuiet {
+		fmt.Printf("\nPress [ENTER] to exit...\n")
+		fmt.Scanln()
+	}
+	os.Exit(0)
+}
+
+func stopFoolingProgramsAndServices(skiptaskkill bool, skipsvckill bool) error {
+	if !skiptaskkill {
+		err := utils.TaskKill(options.MyOptions.Gdpi.ExecutableName, options.MyOptions.Zapret.ExecutableName, options.MyOptions.Ciadpi.ExecutableName)
+		if err != nil {
+			return fmt.Errorf("can't properly terminate fooling programs: %v", err)
+		}
+	}
+	if !skipsvckill {
+		var snames []string
+		snames = append(snames, options.MyOptions.Gdpi.ServiceNames...)
+		snames = append(snames, options.MyOptions.Zapret.ServiceNames...)
+		snames = append(snames, options.MyOptions.Ciadpi.ServiceNames...)
+		snames = append(snames, options.MyOptions.WinDivert.Value...)
+		err := utils.StopAndDeleteServices(snames...)
+		if err != nil {
+			return fmt.Errorf("can't properly stop fooling services: %v", err)
+		}
+	}
+	return nil
+}
+
+func stopFoolingProgramsAndServicesMini(skiptaskkill bool, skipsvckill bool) error {
+	if !skiptaskkill {
+		err := utils.TaskKill(programToUse.ExecutableName)
+		if err != nil {
+			return fmt.Errorf("can't properly terminate fooling programs: %v", err)
+		}
+	}
+	if !skipsvckill {
+		var snames []string
+		snames = append(snames, options.MyOptions.WinDivert.Value...)
+		err := utils.StopAndDeleteServices(snames...)
+		if err != nil {
+			return fmt.Errorf("can't properly stop fooling services: %v", err)
+		}
+	}
+	return nil
+}
+
+func finalResultsShowcase() {
+	if !testBegun {
+		return
+	}
+	totalURLs := len(allWebsites)
+	if totalURLs == 0 {
+		return
+	}
+	totalStrategies := len(allStrategies)
+	if totalStrategies == 0 {
+		return
+	}
+	log.Printf("\n--------------------RESULTS BY URL---------------------\n")
+	var urlsNoSuccess []int
+	for i := 0; i < totalURLs; i++ {
+		if !allWebsites[i].HasSuccesses {
+			urlsNoSuccess = append(urlsNoSuccess, i)
+		}
+	}
+	if len(urlsNoSuccess) > 0 {
+		log.Println("\nURLs with NO successes:")
+		for i := 0; i < len(urlsNoSuccess); i++ {
+			log.Printf("%s | IP: %s%s | Failures: %s%s\n", siteName(allWebsites[urlsNoSuccess[i]]), formatTargets(allWebsites[urlsNoSuccess[i]]), formatAdvertised(allWebsites[urlsNoSuccess[i]]), checklist.FormatOutcomes(allWebsites[urlsNoSuccess[i]].OutcomeCounts), formatRedirects(allWebsites[urlsNoSuccess[i]].Targets[0]))
+		}
+	}
+	if len(urlsNoSuccess) != totalURLs {
+		log.Println("\nURLs with successes:")
+		for i := 0; i < totalURLs; i++ {
+			if allWebsites[i].HasSuccesses {
+				log.Printf("%s | IP: %s%s%s | Best strategy: %s", siteName(allWebsites[i]), formatTargets(allWebsites[i]), formatAdvertised(allWebsites[i]), formatRedirects(allWebsites[i].Targets[0]), allStrategies[allWebsites[i].MostSuccessfulStrategyNum].Keys)
+			}
+		}
+	}
+	var urlsDNSBlocked []int
+	for i := 0; i < totalURLs; i++ {
+		if lookup.IsDNSBlocked(allWebsites[i].DNSVerdict) {
+			urlsDNSBlocked = append(urlsDNSBlocked, i)
+		}
+	}
+	if len(urlsDNSBlocked) > 0 {
+		log.Println("\nURLs blocked by DNS (use a custom resolver for them, DPI bypass alone won't help):")
+		for i := 0; i < len(urlsDNSBlocked); i++ {
+			log.Printf("%s | DNS: %s\n", siteName(allWebsites[urlsDNSBlocked[i]]), allWebsites[urlsDNSBlocked[i]].DNSVerdict)
+		}
+	}
+	var urlsMITM []int
+	for i := 0; i < totalURLs; i++ {
+		if allWebsites[i].SuspectCert != "" {
+			urlsMITM = append(urlsMITM, i)
+		}
+	}
+	if len(urlsMITM) > 0 {
+		log.Println("\nURLs served with substituted certificates (responses from them were not counted):")
+		for i := 0; i < len(urlsMITM); i++ {
+			log.Printf("%s | %s\n", siteName(allWebsites[urlsMITM[i]]), allWebsites[urlsMITM[i]].SuspectCert)
+		}
+	}
+	totalOutcomes := map[string]int{}
+	for i := 0; i < totalURLs; i++ {
+		for outcome, n := range allWebsites[i].OutcomeCounts {
+			totalOutcomes[outcome] += n
+		}
+	}
+	log.Println("\nAll requests by outcome:")
+	for _, outcome := range checklist.Outcomes {
+		if totalOutcomes[outcome] > 0 {
+			log.Printf("%s: %d\n", outcome, totalOutcomes[outcome])
+		}
+	}
+	log.Printf("\n------------------RESULTS BY STRATEGY------------------\n")
+	for i := 0; i <= totalURLs; i++ {
+		var lines []strategy.Strategy
+		for _, strat := range allStrategies {
+			if strat.Successes == i {
+				lines = append(lines, strat)
+			}
+		}
+		if len(lines) > 0 {
+			log.Printf("\nStrategies with %d/%d successes:\n", i, totalURLs)
+			for _, line := range lines {
+				log.Println(line.Keys)
+			}
+		}
+	}
+	if strategy.IsDualStack() {
+		urlsByIPV := countByIPV()
+		for _, ipv := range strategy.IPVs {
+			log.Printf("\n--------------------IPv%d STRATEGIES--------------------\n", ipv)
+			for i := 0; i <= urlsByIPV[ipv]; i++ {
+				var lines []strategy.Strategy
+				for _, strat := range allStrategies {
+					if strat.IsTested && strat.SuccessesByIPV[ipv] == i {
+						lines = append(lines, strat)
+					}
+				}
+				if len(lines) > 0 {
+					log.Printf("\nStrategies with %d/%d IPv%d successes:\n", i, urlsByIPV[ipv], ipv)
+					for _, line := range lines {
+						log.Println(line.Keys)
+					}
+				}
+			}
+		}
+		log.Println("\nStrategies working over every IP version:")
+		for _, strat := range allStrategies {
+			both := strat.IsTested
+			for _, ipv := range strategy.IPVs {
+				if strat.SuccessesByIPV[ipv] == 0 {
+					both = false
+				}
+			}
+			if both {
+				log.Println(strat.Keys)
+			}
+		}
+	}
+	log.Printf("\n----------------------INFORMATION----------------------\n\n")
+	log.Println("Program:", programToUse.ProgramName)
+	mode := "Native"
+	if testMode == 2 {
+		mode = "Curl"
+	}
+	log.Println("Requests mode:", mode)
+	log.Println("Protocol:", strategy.Protocol)
+	log.Println("IP version:", formatIPVs())
+	if strategy.Proxy != "noproxy" {
+		log.Println("Proxy:", strategy.Proxy)
+	}
+	log.Println("Strategies list:", stratlist)
+	log.Println("Checklist:", checklistfile)
+	log.Println("Number of passes:", passes)
+	log.Println("Timeout:", options.MyOptions.ConnTimeout.Value, "sec")
+	if testMode == 1 {
+		log.Println("Redirect policy:", options.MyOptions.RedirectPolicy.Value)
+	}
+	log.Println("TLS profile:", options.MyOptions.TLSProfile.Value)
+	if options.MyOptions.ProbeMode.Value == "volume" {
+		log.Println("Probe: volume,", options.MyOptions.VolumeBytes.Value, "bytes")
+	} else {
+		log.Println("Probe:", options.MyOptions.ProbeMode.Value)
+	}
+	if resolverOfChoice != "" {
+		log.Println("Resolver:", lookup.DescribeResolver(resolverOfChoice))
+	} else {
+		log.Println("Resolver: System")
+	}
+	if localDNSStarted {
+		log.Println("Local DNS:", options.MyOptions.LocalDNSListen.Value)
+	}
+	if options.MyOptions.AutoGGC.Value && ggcURL != "" {
+		log.Println("Google Video Cluster:", ggc)
+		log.Println("Google Video URL:", ggcURL)
+	}
+	testBegun = false
+}
+
+func resolveWebsite(site *checklist.Website) []string {
+	var logs []string
+	domainOnly := utils.InsensitiveReplace(site.Address, "https://", "")
+	dnsResult, err := lookup.DnsLookup(runCtx, resolverOfChoice, domainOnly, site.IPV, options.MyOptions.ResolverNativeTimeout.Value, options.MyOptions.ResolverNativeRetries.Value, options.MyOptions.SkipCertVerify.Value)
+	if err != nil {
+		logs = append(logs, fmt.Sprintf("No response from DNS for '%s' (%v); removing URL from the checklist...", domainOnly, err))
+		return logs
+	}
+	ips := lookup.ExtractAddresses(dnsResult)
+	checklist.SetTargets(site, ips, options.MyOptions.MaxIPsPerSite.Value)
+	if !site.IsResolved {
+		logs = append(logs, fmt.Sprintf("No valid IPv%d was found for '%s' (%s from '%s'); removing URL from the checklist...", site.IPV, domainOnly, lookup.DescribeResult(dnsResult), dnsResult.Resolver))
+		return logs
+	}
+	logs = append(logs, fmt.Sprintf("IPv%d for '%s' was found: %s", site.IPV, domainOnly, checklist.ListIPs(*site)))
+	if dnsResult.FromCache {
+		logs = append(logs, fmt.Sprintf("\t%s | cached %s ago", lookup.DescribeChain(dnsResult), dnsResult.CacheAge.Round(time.Second)))
+	} else {
+		logs = append(logs, fmt.Sprintf("\t%s | %s, %d attempt(s)", lookup.DescribeChain(dnsResult), dnsResult.Latency.Round(time.Millisecond), dnsResult.Attempts))
+	}
+	if len(ips) > len(site.Targets) {
+		logs = append(logs, fmt.Sprintf("Only first %d of %d addresses will be tested", len(site.Targets), len(ips)))
+	}
+	return logs
+}
+
+// empty and unparsable entries are dropped, system resolver is used only when custom ones are off
+func validateResolvers() error {
+	var valid []string
+	for _, resolver := range options.MyOptions.Resolvers.Value {
+		if strings.TrimSpace(resolver) == "" {
+			log.Println("Empty resolver entry, skipping it")
+			skippedResolvers = append(skippedResolvers, resolver)
+			continue
+		}
+		t, err := lookup.ResolverTransport(resolver)
+		if err != nil {
+			log.Printf("Resolver '%s' is invalid, skipping it: %v\n", resolver, err)
+			skippedResolvers = append(skippedResolvers, resolver)
+			continue
+		}
+		log.Printf("Resolver '%s' is valid: %s\n", resolver, t)
+		valid = append(valid, resolver)
+	}
+	if len(valid) == 0 && options.MyOptions.UseDoH.Value {
+		return fmt.Errorf("no valid resolvers in config")
+	}
+	options.MyOptions.Resolvers.Value = valid
+
+	var validBootstrap []string
+	for _, bootstrap := range options.MyOptions.ResolverBootstrap.Value {
+		if strings.TrimSpace(bootstrap) == "" {
+			log.Println("Empty bootstrap resolver entry, skipping it")
+			continue
+		}
+		_, err := lookup.ResolverTransport(bootstrap)
+		if err != nil {
+			log.Printf("Bootstrap resolver '%s' is invalid, skipping it: %v\n", bootstrap, err)
+			continue
+		}
+		validBootstrap = append(validBootstrap, bootstrap)
+	}
+	options.MyOptions.ResolverBootstrap.Value = validBootstrap
+	lookup.SetBootstrap(validBootstrap)
+	return nil
+}
+
+func checkResolver(resolver string, domainOnly string, ipv int) bool {
+	dnsResult, err := lookup.DnsLookupFresh(runCtx, resolver, domainOnly, ipv, options.MyOptions.ResolverNativeTimeout.Value, options.MyOptions.ResolverNativeRetries.Value, options.MyOptions.SkipCertVerify.Value)
+	if err != nil {
+		log.Printf("No proper response from DNS: %v; trying next one...\n", err)
+		return false
+	}
+	ips := lookup.ExtractAddresses(dnsResult)
+	if len(ips) == 0 {
+		log.Printf("No valid IP was found: %s; trying next one...\n", lookup.DescribeResult(dnsResult))
+		return false
+	}
+	log.Printf("Resolver seems ok: '%s' -> %s (%s, %d attempt(s))\n", domainOnly, ips[0], dnsResult.Latency.Round(time.Millisecond), dnsResult.Attempts)
+	return true
+}
+
+func lookupHTTPSRecords(site *checklist.Website) string {
+	domainOnly := utils.InsensitiveReplace(site.Address, "https://", "")
+	info, err := lookup.LookupHTTPS(runCtx, resolverOfChoice, domainOnly, options.MyOptions.ResolverNativeTimeout.Value, options.MyOptions.ResolverNativeRetries.Value, options.MyOptions.SkipCertVerify.Value)
+	if err != nil {
+		return fmt.Sprintf("Can't look up HTTPS record for '%s': %v", domainOnly, err)
+	}
+	if len(info.Records) == 0 {
+		return fmt.Sprintf("No HTTPS record for '%s' (%s)", domainOnly, info.RcodeName)
+	}
+	site.HasHTTPSRecord = true
+	site.ALPN = info.ALPN
+	site.AdvertisesECH = info.ECH
+	site.AdvertisesH3 = info.H3
+	return fmt.Sprintf("HTTPS record for '%s': ALPN %s | ECH: %t | hints: %s", domainOnly, info.ALPN, info.ECH, info.Hints)
+}
+
+func formatAdvertised(site checklist.Website) string {
+	if !site.HasHTTPSRecord {
+		return ""
+	}
+	var a []string
+	if site.AdvertisesECH {
+		a = append(a, "ECH")
+	}
+	if site.AdvertisesH3 {
+		a = append(a, "h3")
+	}
+	if len(a) == 0 {
+		return " | Advertises: -"
+	}
+	return " | Advertises: " + strings.Join(a, ", ")
+}
+
+func logStubQueries(tag string) {
+	queries := lookup.StubQueries(tag)
+	log.Printf("Local DNS queries during this strategy: %d\n", len(queries))
+	for _, q := range queries {
+		if q.Err != "" {
+			log.Printf("\t%s %s: %s\n", q.Qtype, q.Name, q.Err)
+		} else {
+			log.Printf("\t%s %s: %s %s\n", q.Qtype, q.Name, q.Rcode, q.Answer)
+		}
+	}
+}
+
+func formatOutcome(target checklist.Target) string {
+	if target.LastOutcome == checklist.OutcomeSuccess || target.LastOutcome == "" {
+		return ""
+	}
+	if target.LastError == "" {
+		return fmt.Sprintf(" | %s", target.LastOutcome)
+	}
+	return fmt.Sprintf(" | %s: %s", target.LastOutcome, target.LastError)
+}
+
+func formatRedirects(target checklist.Target) string {
+	if len(target.RedirectChain) == 0 {
+		return ""
+	}
+	return " | Redirects: " + strings.Join(target.RedirectChain, " -> ")
+}
+
+func formatHandshake(target checklist.Target) string {
+	if target.TLSVersion == "" {
+		return ""
+	}
+	alpn := target.ALPN
+	if alpn == "" {
+		alpn = "no ALPN"
+	}
+	return fmt.Sprintf(" | %s, %s, %s, %s", target.TLSVersion, alpn, target.CertSubject, target.HandshakeTime.Round(time.Millisecond))
+}
+
+func formatCert(target checklist.Target) string {
+	if target.LastOutcome != checklist.OutcomeMITM {
+		return ""
+	}
+	return fmt.Sprintf(" | Issuer: %s, SPKI: %s, valid until %s", target.CertIssuer, target.CertSPKI, target.CertNotAfter.Format(time.DateOnly))
+}
+
+func siteName(site checklist.Website) string {
+	if !strategy.IsDualStack() {
+		return site.Address
+	}
+	return fmt.Sprintf("%s (IPv%d)", site.Address, site.IPV)
+}
+
+func countByIPV() map[int]int {
+	urls := map[int]int{}
+	for _, site := range allWebsites {
+		urls[site.IPV]++
+	}
+	return urls
+}
+
+func formatIPVs() string {
+	var v []string
+	for _, ipv := range strategy.IPVs {
+		v = append(v, strconv.Itoa(ipv))
+	}
+	return strings.Join(v, ", ")
+}
+
+func formatVolume(target checklist.Target) string {
+	if options.MyOptions.ProbeMode.Value != "volume" {
+		return ""
+	}
+	return fmt.Sprintf(" | %d/%d bytes, %.1f KB/s", target.BytesReceived, options.MyOptions.VolumeBytes.Value, target.Throughput/1024)
+}
+
+func formatTargets(site checklist.Website) string {
+	if len(site.Targets) == 1 {
+		return site.Targets[0].IP
+	}
+	var ips []string
+	for _, target := range site.Targets {
+		ips = append(ips, fmt.Sprintf("%s (%d/%d)", target.IP, target.Successes, target.Attempts))
+	}
+	return strings.Join(ips, ", ")
+}
+
+func userChooseContinueInsecure() error {
+	_, _choiceN, err := gochoice.Pick(
+		fmt.Sprintln("\nATTENTION: Normal connectivity test failed, but insecure connectivity test succeeded\nEither root certificates are corrupted or your firewall/antivirus software is interfering\n\nContinue anyway? Test results may be compromised if your ISP performs a certificate substitution:"),
+		[]string{"Continue in insecure mode", "Exit"},
+	)
+	if err != nil {
+		return fmt.Errorf("problem with a gochoice: %v", err)
+	}
+	if _choiceN == 1 {
+		exitByChoice()
+	}
+	log.Println("Continuing in insecure mode by user's choice")
+	return nil
+}
+
+func userChooseProgram() (options.OptionFoolingProgram, error) {
+	var programList []string
+	if options.MyOptions.Gdpi.IsExist {
+		programList = append(programList, options.MyOptions.Gdpi.ProgramName)
+	}
+	if options.MyOptions.Zapret.IsExist {
+		programList = append(programList, options.MyOptions.Zapret.ProgramName)
+	}
+	if options.MyOptions.Ciadpi.IsExist {
+		programList = append(programList, options.MyOptions.Ciadpi.ProgramName)
+	}
+	programList = append(programList, "Exit")
+
+	choice, _, err := gochoice.Pick(
+		fmt.Sprintf("\nATTENTION: '%s' '%s' and '%s' will be closed, their services will be removed\n\nChoose the fooling program to use:", options.MyOptions.Gdpi.ProgramName, options.MyOptions.Zapret.ProgramName, options.MyOptions.Ciadpi.ProgramName),
+		programList,
+	)
+	if err != nil {
+		var p options.OptionFoolingProgram
+		return p, fmt.Errorf("problem with a gochoice: %v", err)
+	}
+	switch choice {
+	case options.MyOptions.Gdpi.ProgramName:
+		log.Printf("Proceeding with '%s'\n", choice)
+		return options.MyOptions.Gdpi, nil
+	case options.MyOptions.Zapret.ProgramName:
+		log.Printf("Proceeding with '%s'\n", choice)
+		return options.MyOptions.Zapret, nil
+	case options.MyOptions.Ciadpi.ProgramName:
+		log.Printf("Proceeding with '%s'\n", choice)
+		return options.MyOptions.Ciadpi, nil
+	case "Exit":
+		exitByChoice()
+	}
+	var p options.OptionFoolingProgram
+	return p, fmt.Errorf("schrodinger's cat: choice out of bounds: '%s'", choice)
+}
+
+func userChooseTestMode() (int, error) {
+	var modes []string
+	if strategy.Proxy != "noproxy" {
+		err := nativeProxySupported()
+		if err == nil {
+			modes = []string{"Use Native (faster)", "Use Curl (reliable)"}
+		} else {
+			log.Printf("Native mode is unavailable: %v\n", err)
+			modes = []string{"Use Curl (only this mode is available with this proxy)"}
+		}
+	} else {
+		modes = []string{"Use Native (faster)", "Use Curl (reliable)"}
+	}
+	modes = append(modes, "Exit")
+	choice, _, err := gochoice.Pick(
+		"\nChoose requests mode:",
+		modes,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("problem with a gochoice: %v", err)
+	}
+	switch choice {
+	case "Exit":
+		exitByChoice()
+	case "Use Native (faster)":
+		log.Println("Proceeding with 'Native'")
+		return 1, nil
+	case "Use Curl (reliable)":
+		log.Println("Proceeding with 'Curl'")
+		return 2, nil
+	case "Use Curl (only this mode is available with this proxy)":
+		log.Println("Proceeding with 'Curl' (forced by the use of proxy)")
+		return 2, nil
+	}
+	return 0, fmt.Errorf("schrodinger's cat: choice out of bounds: '%s'", choice)
+}
+
+func nativeProxySupported() error {
+	if strategy.Protocol == "UDP" {
+		return fmt.Errorf("QUIC can't be sent through a proxy")
+	}
+	_, err := requestsnative.ParseProxy(strategy.Proxy)
+	return err
+}
+
+func userChoosePasses() (int, error) {
+	var passesVariants = []string{"1", "2", "3", "4", "5", "6", "7", "8", "9"}
+
+	passesVariants = append(passesVariants, "Exit")
+	choice, choiceN, err := gochoice.Pick(
+		"\nNumber of passes:",
+		passesVariants,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("problem with a gochoice: %v", err)
+	}
+	if choice == "Exit" {
+		exitByChoice()
+	}
+	log.Printf("Proceeding with '%d' pass(es)\n", (choiceN + 1))
+	return (choiceN + 1), nil
+}
+
+func userChooseChecklist() (string, error) {
+	log.Printf("Reading folder '%s'...\n", CHECKLISTFOLDER)
+	entries, err := os.ReadDir(CHECKLISTFOLDER)
+	if err != nil {
+		return "", fmt.Errorf("can't read folder '%s': %v", CHECKLISTFOLDER, err)
+	}
+
+	var checklistsInFolder []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			checklistsInFolder = append(checklistsInFolder, e.Name())
+		}
+	}
+	if len(checklistsInFolder) == 0 {
+		return "", fmt.Errorf("can't find any checklists in folder '%s'", CHECKLISTFOLDER)
+	}
+
+	checklistsInFolder = append(checklistsInFolder, "Exit")
+	choice, _, err := gochoice.Pick(
+		"\nChoose the checklist:",
+		checklistsInFolder,
+	)
+	if err != nil {
+		return "", fmt.Errorf("problem with a gochoice: %v", err)
+	}
+	if choice == "Exit" {
+		exitByChoice()
+	}
+	log.Printf("Proceeding with '%s'\n", choice)
+
+	return choice, nil
+}
+
+func userChooseStrategyList() (string, error) {
+	fullpath := filepath.Join(STRATEGYFOLDER, programToUse.ProgramName)
+	log.Printf("Reading folder '%s'...\n", fullpath)
+	entries, err := os.ReadDir(fullpath)
+	if err != nil {
+		return "", fmt.Errorf("can't read folder '%s': %v", fullpath, err)
+	}
+
+	var strategiesListsInFolder []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			strategiesListsInFolder = append(strategiesListsInFolder, e.Name())
+		}
+	}
+	if len(strategiesListsInFolder) == 0 {
+		return "", fmt.Errorf("can't find any strategy lists in folder '%s'", fullpath)
+	}
+
+	strategiesListsInFolder = append(strategiesListsInFolder, "Exit")
+	choice, _, err := gochoice.Pick(
+		"\nChoose the strategy list:",
+		strategiesListsInFolder,
+	)
+	if err != nil {
+		return "", fmt.Errorf("problem with a gochoice: %v", err)
+	}
+	if choice == "Exit" {
+		exitByChoice()
+	}
+	log.Printf("Proceeding with '%s'\n", choice)
+
+	return choice, nil
+}
+
+func exitByChoice() {
+	log.Printf("\nExiting by users choice...\n")
+	os.Exit(0)
+}
+
+func check(err error) {
+	switch err {
+	case nil:
+		return
+
+	case errInterrupt:
+		log.Printf("\nSignal catched: %v\n", err)
+
+		if testBegun {
+			// stopping programs and services
+			log.Printf("\nTest interrupted, stopping still active fooling programs and services, further errors will be silently ignored at this point...\n")
+			stopFoolingProgramsAndServicesMini(*flagSkipTaskKill, *flagSkipSvcKill)
+			supervisor.RestoreDNS()
+			lookup.StopStub()
+
+			showcaseOnce.Do(finalResultsShowcase)
+		}
+
+		supervisor.Teardown()
+		log.Printf("\nExiting with an interrupt...\n")
+		os.Exit(1)
+
+	default:
+		log.Printf("\nCritical error: %v\n", err)
+
+		if testBegun {
+			// stopping programs and services
+			log.Printf("\nTest failed, stopping still active fooling programs and services, further errors will be silently ignored at this point...\n")
+			stopFoolingProgramsAndServicesMini(*flagSkipTaskKill, *flagSkipSvcKill)
+			supervisor.RestoreDNS()
+			lookup.StopStub()
+
+			showcaseOnce.Do(finalResultsShowcase)
+		} else {
+			if !*flagIsQuiet {
+				fmt.Printf("\nPress [ENTER] to exit...\n")
+				fmt.Scanln()
+			}
+		}
+
+		supervisor.Teardown()
+		log.Printf("\nExiting with an error...\n")
+		os.Exit(1)
+	}
+}
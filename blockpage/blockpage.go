@@ -0,0 +1,156 @@
+package blockpage
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"goodcheckgogo/utils"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// only this much of the body is read for matching
+const MaxBody = 64 * 1024
+
+type Response struct {
+	IP          string
+	StatusCode  int
+	RedirectURL string
+	Body        []byte
+}
+
+type Fingerprints struct {
+	Hosts      []string
+	Statuses   []int
+	BodyHashes []string
+	Substrings []string
+	IPs        []string
+}
+
+// well-known stubs; anything else goes to the file
+var fingerprints = Fingerprints{
+	Hosts:      []string{"warning.rt.ru", "zapret-info.gov.ru", "eais.rkn.gov.ru", "blocklist.rkn.gov.ru"},
+	Statuses:   []int{451},
+	BodyHashes: nil,
+	Substrings: []string{"zapret-info.gov.ru", "eais.rkn.gov.ru", "blocklist.rkn.gov.ru"},
+	IPs:        nil,
+}
+
+func Load(file string) error {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		log.Printf("No fingerprints file '%s', using built-in fingerprints only\n", file)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("can't open a file '%s': %v", file, err)
+	}
+	defer f.Close()
+
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		line := strings.TrimSpace(scan.Text())
+		if utils.IsCommented(line, "/") {
+			continue
+		}
+		err = parseFingerprint(line)
+		if err != nil {
+			return fmt.Errorf("can't parse a line '%s': %v", line, err)
+		}
+	}
+	if err = scan.Err(); err != nil {
+		return fmt.Errorf("can't read a file '%s': %v", file, err)
+	}
+	log.Printf("Block-page fingerprints: %d hosts, %d status codes, %d body hashes, %d substrings, %d IPs\n", len(fingerprints.Hosts), len(fingerprints.Statuses), len(fingerprints.BodyHashes), len(fingerprints.Substrings), len(fingerprints.IPs))
+	return nil
+}
+
+// lines look like 'host:warning.example.net', 'status:451', 'sha256:<hex>', 'body:<text>' or 'ip:192.0.2.1'
+func parseFingerprint(line string) error {
+	kind, value, ok := strings.Cut(line, ":")
+	if !ok || value == "" {
+		return fmt.Errorf("expected 'type:value'")
+	}
+	switch strings.ToLower(kind) {
+	case "host":
+		fingerprints.Hosts = append(fingerprints.Hosts, strings.ToLower(value))
+	case "status":
+		code, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("can't convert status code '%s' to integer: %v", value, err)
+		}
+		fingerprints.Statuses = append(fingerprints.Statuses, code)
+	case "sha256":
+		if _, err := hex.DecodeString(value); err != nil || len(value) != 64 {
+			return fmt.Errorf("'%s' is not a sha256 hex digest", value)
+		}
+		fingerprints.BodyHashes = append(fingerprints.BodyHashes, strings.ToLower(value))
+	case "body":
+		fingerprints.Substrings = append(fingerprints.Substrings, value)
+	case "ip":
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return fmt.Errorf("'%s' is not an IP", value)
+		}
+		fingerprints.IPs = append(fingerprints.IPs, ip.String())
+	default:
+		return fmt.Errorf("unknown fingerprint type '%s'", kind)
+	}
+	return nil
+}
+
+// IPs are stub addresses, DNS diagnosis treats them as a sign of tampering
+func IPs() []string {
+	return slices.Clone(fingerprints.IPs)
+}
+
+func MatchHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, h := range fingerprints.Hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns the reason when response looks like a block page
+func Match(r Response) (bool, string) {
+	if ip := net.ParseIP(r.IP); ip != nil && slices.Contains(fingerprints.IPs, ip.String()) {
+		return true, fmt.Sprintf("stub IP %s", r.IP)
+	}
+	if slices.Contains(fingerprints.Statuses, r.StatusCode) {
+		return true, fmt.Sprintf("status %d", r.StatusCode)
+	}
+	if r.RedirectURL != "" {
+		u, err := url.Parse(r.RedirectURL)
+		if err == nil && MatchHost(u.Hostname()) {
+			return true, fmt.Sprintf("redirect to %s", u.Hostname())
+		}
+	}
+	if len(r.Body) == 0 {
+		return false, ""
+	}
+	body := r.Body
+	if len(body) > MaxBody {
+		body = body[:MaxBody]
+	}
+	if len(fingerprints.BodyHashes) > 0 {
+		sum := sha256.Sum256(body)
+		if slices.Contains(fingerprints.BodyHashes, hex.EncodeToString(sum[:])) {
+			return true, "body hash"
+		}
+	}
+	for _, s := range fingerprints.Substrings {
+		if strings.Contains(string(body), s) {
+			return true, fmt.Sprintf("body contains '%s'", s)
+		}
+	}
+	return false, ""
+}
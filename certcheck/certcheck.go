@@ -0,0 +1,155 @@
+package certcheck
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"goodcheckgogo/utils"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Chain struct {
+	// base64 of sha256 over the leaf public key, the same form HPKP used
+	SPKI      string
+	Subject   string
+	Issuer    string
+	NotBefore time.Time
+	NotAfter  time.Time
+	Trusted   bool
+	// empty when the chain looks genuine
+	Verdict string
+}
+
+type pinSet struct {
+	SPKIs   []string
+	Issuers []string
+}
+
+// host -> expected keys and issuers
+var pins = map[string]*pinSet{}
+
+func Load(file string) error {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		log.Printf("No certificate pins file '%s', checking against system certificates only\n", file)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("can't open a file '%s': %v", file, err)
+	}
+	defer f.Close()
+
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		line := strings.TrimSpace(scan.Text())
+		if utils.IsCommented(line, "/") {
+			continue
+		}
+		err = parsePin(line)
+		if err != nil {
+			return fmt.Errorf("can't parse a line '%s': %v", line, err)
+		}
+	}
+	if err = scan.Err(); err != nil {
+		return fmt.Errorf("can't read a file '%s': %v", file, err)
+	}
+	log.Printf("Certificate pins: %d hosts\n", len(pins))
+	return nil
+}
+
+// lines look like 'example.com spki:<base64 sha256>' or 'example.com issuer:Let's Encrypt'
+func parsePin(line string) error {
+	host, value, ok := strings.Cut(line, " ")
+	if !ok {
+		return fmt.Errorf("expected 'host type:value'")
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	kind, value, ok := strings.Cut(strings.TrimSpace(value), ":")
+	if !ok || value == "" {
+		return fmt.Errorf("expected 'host type:value'")
+	}
+	p, ok := pins[host]
+	if !ok {
+		p = &pinSet{}
+		pins[host] = p
+	}
+	switch strings.ToLower(kind) {
+	case "spki":
+		if b, err := base64.StdEncoding.DecodeString(value); err != nil || len(b) != sha256.Size {
+			return fmt.Errorf("'%s' is not a base64 sha256 digest", value)
+		}
+		p.SPKIs = append(p.SPKIs, value)
+	case "issuer":
+		p.Issuers = append(p.Issuers, value)
+	default:
+		return fmt.Errorf("unknown pin type '%s'", kind)
+	}
+	return nil
+}
+
+// pins for a domain cover its subdomains too
+func lookupPins(host string) *pinSet {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for {
+		if p, ok := pins[host]; ok {
+			return p
+		}
+		_, parent, ok := strings.Cut(host, ".")
+		if !ok {
+			return nil
+		}
+		host = parent
+	}
+}
+
+// Inspect verifies the chain on its own, so substitution is caught even when requests skip verification
+func Inspect(host string, certs []*x509.Certificate) Chain {
+	if len(certs) == 0 {
+		return Chain{Verdict: "no certificate"}
+	}
+	leaf := certs[0]
+	sum := sha256.Sum256(leaf.RawSubjectPublicKeyInfo)
+	c := Chain{
+		SPKI:      base64.StdEncoding.EncodeToString(sum[:]),
+		Subject:   leaf.Subject.String(),
+		Issuer:    leaf.Issuer.String(),
+		NotBefore: leaf.NotBefore,
+		NotAfter:  leaf.NotAfter,
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	// nil roots mean the system pool
+	_, err := leaf.Verify(x509.VerifyOptions{
+		DNSName:       host,
+		Intermediates: intermediates,
+	})
+	if err != nil {
+		c.Verdict = fmt.Sprintf("not trusted by system: %v", err)
+		return c
+	}
+	c.Trusted = true
+
+	p := lookupPins(host)
+	if p == nil {
+		return c
+	}
+	if slices.Contains(p.SPKIs, c.SPKI) {
+		return c
+	}
+	for _, issuer := range p.Issuers {
+		if strings.Contains(c.Issuer, issuer) {
+			return c
+		}
+	}
+	c.Verdict = "doesn't match pinned keys or issuers"
+	return c
+}
@@ -1,94 +1,401 @@
-package checklist
-
-import (
-	"bufio"
-	"fmt"
-	"goodcheckgogo/utils"
-	"log"
-	"os"
-	"strings"
-)
-
-type Website struct {
-	Address                         string
-	IP                              string
-	IsResolved                      bool
-	HasSuccesses                    bool
-	MostSuccessfulStrategyNum       int
-	MostSuccessfulStrategySuccesses int
-	LastResponseCode                int
-}
-
-func NewWebsite(addr string) Website {
-	w := Website{
-		Address:                         addr,
-		IP:                              "unknown",
-		IsResolved:                      false,
-		HasSuccesses:                    false,
-		MostSuccessfulStrategyNum:       -1,
-		MostSuccessfulStrategySuccesses: -1,
-		LastResponseCode:                -1,
-	}
-	return w
-}
-
-func ReadChecklist(file string) ([]Website, error) {
-	f, err := os.Open(file)
-	if err != nil {
-		return nil, fmt.Errorf("can't open a file '%s': %v", file, err)
-	}
-	defer f.Close()
-
-	var w []Website
-
-	scan := bufio.NewScanner(f)
-	for scan.Scan() {
-		if !utils.IsCommented(scan.Text(), "/") {
-			addr := cleanURL(scan.Text())
-			log.Println("URL to check:", addr)
-			w = append(w, NewWebsite(addr))
-		}
-	}
-	return w, nil
-}
-
-func cleanURL(url string) string {
-	withReplaces := utils.InsensitiveReplace(url, "http://", "")
-	withReplaces = utils.InsensitiveReplace(withReplaces, "https://", "")
-
-	withReplaces = strings.Split(withReplaces, `/`)[0]
-
-	withReplaces = "https://" + withReplaces
-
-	return withReplaces
-}
-
-var (
-	//uzpkfa50vqlgb61wrmhc72xsnid83ytoje94-
-	//0123456789abcdefghijklmnopqrstuvwxyz-
-	//clusterDecodeArrayA = [...]string{"u", "z", "p", "k", "f", "a", "5", "0", "v", "q", "l", "g", "b", "6",
-	//	"1", "w", "r", "m", "h", "c", "7", "2", "x", "s", "n", "i", "d", "8", "3", "y", "t", "o", "j", "e", "9", "4", "-"}
-	//clusterDecodeArrayB = [...]string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9", "a", "b", "c", "d",
-	//	"e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p", "q", "r", "s", "t", "u", "v", "w", "x", "y", "z", "-"}
-	//elsz6dkry5cjqx4bipw3ahov29gnu08fmt1
-	//uzpkfa50vqlgb61wrmhc72xsnid83ytoje94
-	clusterDecodeArrayA = [...]string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9", "a", "b", "c", "d", "e",
-		"f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p", "q", "r", "s", "t", "u", "v", "w", "x", "y", "z", "-"}
-	clusterDecodeArrayB = [...]string{"7", "e", "l", "s", "z", "6", "d", "k", "r", "y", "5", "c", "j", "q", "x",
-		"4", "b", "i", "p", "w", "3", "a", "h", "o", "v", "2", "9", "g", "n", "u", "0", "8", "f", "m", "t", "1", "-"}
-)
-
-func ConvertClusterToURL(codename string) string {
-	decodedCodename := ""
-	for _, letter := range codename {
-		l := string(letter)
-		for i := 0; i < len(clusterDecodeArrayA); i++ {
-			if l == clusterDecodeArrayA[i] {
-				decodedCodename = decodedCodename + clusterDecodeArrayB[i]
-				break
-			}
-		}
-	}
-	decodedCodename = "https://rr1---sn-" + decodedCodename + ".googlevideo.com"
-	return decodedCodename
-}
+package checklist
+
+import (
+	"bufio"
+	"fmt"
+	"goodcheckgogo/utils"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	OutcomeSuccess      = "success"
+	OutcomeDNS          = "dns"
+	OutcomeTCPTimeout   = "tcp-timeout"
+	OutcomeTCPRST       = "tcp-rst"
+	OutcomeTLSTimeout   = "tls-timeout"
+	OutcomeTLSAlert     = "tls-alert"
+	OutcomeCertMismatch = "cert-mismatch"
+	OutcomeMITM         = "mitm"
+	OutcomeHTTPTimeout  = "http-timeout"
+	OutcomeQUICTimeout  = "quic-timeout"
+	OutcomeBlockpage    = "blockpage"
+	OutcomeStall        = "stall"
+	OutcomeShortBody    = "short-body"
+	OutcomeRedirect     = "redirect"
+	OutcomeOther        = "other"
+)
+
+// order in which outcomes are displayed
+var Outcomes = []string{OutcomeSuccess, OutcomeDNS, OutcomeTCPTimeout, OutcomeTCPRST, OutcomeTLSTimeout, OutcomeTLSAlert, OutcomeCertMismatch, OutcomeMITM, OutcomeHTTPTimeout, OutcomeQUICTimeout, OutcomeBlockpage, OutcomeStall, OutcomeShortBody, OutcomeRedirect, OutcomeOther}
+
+type Website struct {
+	Address                         string
+	Targets                         []Target
+	IsResolved                      bool
+	IsPinned                        bool
+	PinnedIPs                       []string
+	DNSVerdict                      string
+	HasHTTPSRecord                  bool
+	ALPN                            []string
+	AdvertisesECH                   bool
+	AdvertisesH3                    bool
+	HasSuccesses                    bool
+	MostSuccessfulStrategyNum       int
+	MostSuccessfulStrategySuccesses int
+	LastResponseCode                int
+	OutcomeCounts                   map[string]int
+	VolumeURL                       string
+	RedirectAllow                   []string
+	TLSProfile                      string
+	PlainHTTP                       bool
+	SuspectCert                     string
+	IPV                             int
+}
+
+type Target struct {
+	IP               string
+	LastResponseCode int
+	LastOutcome      string
+	LastError        string
+	OutcomeHistory   []string
+	BytesReceived    int64
+	Throughput       float64
+	RedirectChain    []string
+	TLSVersion       string
+	ALPN             string
+	CertSubject      string
+	CertIssuer       string
+	CertSPKI         string
+	CertNotAfter     time.Time
+	HandshakeTime    time.Duration
+	Successes        int
+	Attempts         int
+}
+
+func NewWebsite(addr string) Website {
+	w := Website{
+		Address:                         addr,
+		Targets:                         nil,
+		IsResolved:                      false,
+		IsPinned:                        false,
+		PinnedIPs:                       nil,
+		DNSVerdict:                      "",
+		HasHTTPSRecord:                  false,
+		ALPN:                            nil,
+		AdvertisesECH:                   false,
+		AdvertisesH3:                    false,
+		HasSuccesses:                    false,
+		MostSuccessfulStrategyNum:       -1,
+		MostSuccessfulStrategySuccesses: -1,
+		LastResponseCode:                -1,
+		OutcomeCounts:                   map[string]int{},
+		VolumeURL:                       "",
+		RedirectAllow:                   nil,
+		TLSProfile:                      "",
+		PlainHTTP:                       false,
+		SuspectCert:                     "",
+		IPV:                             0,
+	}
+	return w
+}
+
+func NewTarget(ip string) Target {
+	t := Target{
+		IP:               ip,
+		LastResponseCode: -1,
+		LastOutcome:      "",
+		LastError:        "",
+		OutcomeHistory:   nil,
+		BytesReceived:    0,
+		Throughput:       0,
+		RedirectChain:    nil,
+		TLSVersion:       "",
+		ALPN:             "",
+		CertSubject:      "",
+		CertIssuer:       "",
+		CertSPKI:         "",
+		CertNotAfter:     time.Time{},
+		HandshakeTime:    0,
+		Successes:        0,
+		Attempts:         0,
+	}
+	return t
+}
+
+func ReadChecklist(file string) ([]Website, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("can't open a file '%s': %v", file, err)
+	}
+	defer f.Close()
+
+	var w []Website
+
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		if !utils.IsCommented(scan.Text(), "/") {
+			fields := strings.Fields(scan.Text())
+			if len(fields) == 0 {
+				continue
+			}
+			line, pinned, err := parsePinnedIPs(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("can't parse a line '%s': %v", scan.Text(), err)
+			}
+			addr := cleanURL(line)
+			site := NewWebsite(addr)
+			err = parseSiteOptions(&site, fields[1:])
+			if err != nil {
+				return nil, fmt.Errorf("can't parse a line '%s': %v", scan.Text(), err)
+			}
+			if len(pinned) > 0 {
+				site.IsPinned = true
+				site.PinnedIPs = pinned
+				site.Targets = []Target{NewTarget(pinned[0])}
+				site.IsResolved = true
+				log.Printf("URL to check: %s | Pinned IP(s): %s\n", addr, pinned)
+			} else {
+				log.Println("URL to check:", addr)
+			}
+			w = append(w, site)
+		}
+	}
+	return w, nil
+}
+
+// pins follow '=' in the host part ('host=ip1,ip2/path'); '=' after '/' or '?' belongs to the URL
+func parsePinnedIPs(line string) (string, []string, error) {
+	line = strings.TrimSpace(line)
+	start := 0
+	if i := strings.Index(line, "://"); i >= 0 {
+		start = i + 3
+	}
+	end := len(line)
+	if i := strings.IndexAny(line[start:], "/?#"); i >= 0 {
+		end = start + i
+	}
+	eq := strings.Index(line[start:end], "=")
+	if eq < 0 {
+		return line, nil, nil
+	}
+	eq += start
+	if eq == start {
+		return "", nil, fmt.Errorf("host is empty")
+	}
+	if strings.TrimSpace(line[eq+1:end]) == "" {
+		return "", nil, fmt.Errorf("pinned IP list is empty")
+	}
+	var ips []string
+	for _, ip := range strings.Split(line[eq+1:end], ",") {
+		ip = strings.Trim(strings.TrimSpace(ip), "[]")
+		if net.ParseIP(ip) == nil {
+			return "", nil, fmt.Errorf("'%s' isn't a valid IP address", ip)
+		}
+		ips = append(ips, ip)
+	}
+	return line[:eq] + line[end:], ips, nil
+}
+
+// per-site options follow the host as 'key:value' tokens
+func parseSiteOptions(site *Website, tokens []string) error {
+	for _, token := range tokens {
+		key, value, ok := strings.Cut(token, ":")
+		if !ok || value == "" {
+			return fmt.Errorf("expected 'key:value', got '%s'", token)
+		}
+		switch strings.ToLower(key) {
+		case "volume":
+			u, err := url.Parse(value)
+			if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+				return fmt.Errorf("'%s' isn't a valid URL for volume probe", value)
+			}
+			site.VolumeURL = value
+			log.Printf("Volume probe URL for '%s': %s\n", site.Address, value)
+		case "redirect":
+			for _, host := range strings.Split(value, ",") {
+				if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+					site.RedirectAllow = append(site.RedirectAllow, host)
+				}
+			}
+			log.Printf("Allowed redirect targets for '%s': %s\n", site.Address, site.RedirectAllow)
+		case "tls":
+			site.TLSProfile = value
+			log.Printf("TLS profile for '%s': %s\n", site.Address, value)
+		case "proto":
+			switch strings.ToUpper(value) {
+			case "HTTP":
+				site.PlainHTTP = true
+			case "HTTPS":
+				site.PlainHTTP = false
+			default:
+				return fmt.Errorf("protocol '%s' is incorrect: expected HTTP or HTTPS", value)
+			}
+			log.Printf("Protocol for '%s': %s\n", site.Address, strings.ToUpper(value))
+		default:
+			return fmt.Errorf("unknown site option '%s'", key)
+		}
+	}
+	return nil
+}
+
+// RequestURL is where the probe goes; plain HTTP probes hit port 80 with the same Host
+func RequestURL(site Website) string {
+	if site.PlainHTTP {
+		return "http://" + utils.InsensitiveReplace(site.Address, "https://", "")
+	}
+	return site.Address
+}
+
+// site is judged by eTLD+1, so 'bbc.co.uk' and 'news.bbc.co.uk' are the same site while 'evil.com' and 'evil.org' are not
+func SameSite(host1 string, host2 string) bool {
+	site1, err1 := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(host1))
+	site2, err2 := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(host2))
+	if err1 != nil || err2 != nil {
+		return strings.EqualFold(host1, host2)
+	}
+	return site1 == site2
+}
+
+func IsAllowedRedirect(host string, allowlist []string) bool {
+	host = strings.ToLower(host)
+	for _, allowed := range allowlist {
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// ForEachIPV copies every site once per IP version, so each version is resolved, tested and reported on its own
+func ForEachIPV(sites []Website, ipvs []int) []Website {
+	var w []Website
+	for _, ipv := range ipvs {
+		for _, site := range sites {
+			site.IPV = ipv
+			site.Targets = slices.Clone(site.Targets)
+			site.OutcomeCounts = map[string]int{}
+			w = append(w, site)
+		}
+	}
+	return w
+}
+
+func PinnedIPsForVersion(site Website, ipv int) []string {
+	var ips []string
+	for _, ip := range site.PinnedIPs {
+		isV4 := net.ParseIP(ip).To4() != nil
+		if (ipv == 4 && isV4) || (ipv == 6 && !isV4) {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+func SetTargets(site *Website, ips []string, maxIPs int) {
+	if maxIPs > 0 && len(ips) > maxIPs {
+		ips = ips[:maxIPs]
+	}
+	site.Targets = nil
+	for _, ip := range ips {
+		site.Targets = append(site.Targets, NewTarget(ip))
+	}
+	site.IsResolved = len(site.Targets) > 0
+}
+
+func ListIPs(site Website) []string {
+	var ips []string
+	for _, t := range site.Targets {
+		ips = append(ips, t.IP)
+	}
+	return ips
+}
+
+func SummarizeTargets(site *Website, thresholdPercent int) int {
+	succeeded, code := 0, 0
+	for i := range site.Targets {
+		site.Targets[i].Attempts++
+		if site.Targets[i].LastOutcome == "" {
+			site.Targets[i].LastOutcome = OutcomeOther
+		}
+		site.Targets[i].OutcomeHistory = append(site.Targets[i].OutcomeHistory, site.Targets[i].LastOutcome)
+		site.OutcomeCounts[site.Targets[i].LastOutcome]++
+		if site.Targets[i].LastOutcome == OutcomeMITM {
+			site.SuspectCert = fmt.Sprintf("issuer %s, SPKI %s", site.Targets[i].CertIssuer, site.Targets[i].CertSPKI)
+		}
+		if site.Targets[i].LastResponseCode > 0 {
+			site.Targets[i].Successes++
+			succeeded++
+			if code == 0 {
+				code = site.Targets[i].LastResponseCode
+			}
+		}
+	}
+	if thresholdPercent > 100 {
+		thresholdPercent = 100
+	}
+	site.LastResponseCode = 0
+	if succeeded > 0 && succeeded*100 >= thresholdPercent*len(site.Targets) {
+		site.LastResponseCode = code
+		site.HasSuccesses = true
+	}
+	return succeeded
+}
+
+func FormatOutcomes(counts map[string]int) string {
+	var parts []string
+	for _, outcome := range Outcomes {
+		if counts[outcome] > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", outcome, counts[outcome]))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func cleanURL(url string) string {
+	withReplaces := utils.InsensitiveReplace(url, "http://", "")
+	withReplaces = utils.InsensitiveReplace(withReplaces, "https://", "")
+
+	withReplaces = strings.Split(withReplaces, `/`)[0]
+
+	withReplaces = "https://" + withReplaces
+
+	return withReplaces
+}
+
+var (
+	//uzpkfa50vqlgb61wrmhc72xsnid83ytoje94-
+	//0123456789abcdefghijklmnopqrstuvwxyz-
+	//clusterDecodeArrayA = [...]string{"u", "z", "p", "k", "f", "a", "5", "0", "v", "q", "l", "g", "b", "6",
+	//	"1", "w", "r", "m", "h", "c", "7", "2", "x", "s", "n", "i", "d", "8", "3", "y", "t", "o", "j", "e", "9", "4", "-"}
+	//clusterDecodeArrayB = [...]string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9", "a", "b", "c", "d",
+	//	"e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p", "q", "r", "s", "t", "u", "v", "w", "x", "y", "z", "-"}
+	//elsz6dkry5cjqx4bipw3ahov29gnu08fmt1
+	//uzpkfa50vqlgb61wrmhc72xsnid83ytoje94
+	clusterDecodeArrayA = [...]string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9", "a", "b", "c", "d", "e",
+		"f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p", "q", "r", "s", "t", "u", "v", "w", "x", "y", "z", "-"}
+	clusterDecodeArrayB = [...]string{"7", "e", "l", "s", "z", "6", "d", "k", "r", "y", "5", "c", "j", "q", "x",
+		"4", "b", "i", "p", "w", "3", "a", "h", "o", "v", "2", "9", "g", "n", "u", "0", "8", "f", "m", "t", "1", "-"}
+)
+
+func ConvertClusterToURL(codename string) string {
+	decodedCodename := ""
+	for _, letter := range codename {
+		l := string(letter)
+		for i := 0; i < len(clusterDecodeArrayA); i++ {
+			if l == clusterDecodeArrayA[i] {
+				decodedCodename = decodedCodename + clusterDecodeArrayB[i]
+				break
+			}
+		}
+	}
+	decodedCodename = "https://rr1---sn-" + decodedCodename + ".googlevideo.com"
+	return decodedCodename
+}
@@ -0,0 +1,119 @@
+package lookup
+
+import (
+	"context"
+	"goodcheckgogo/supervisor"
+	"goodcheckgogo/utils"
+	"slices"
+	"sort"
+	"time"
+)
+
+type ResolverScore struct {
+	Resolver      string
+	Transport     string
+	Queries       int
+	Failures      int
+	Empty         int
+	Compared      int
+	Inconsistent  int
+	AvgLatency    time.Duration
+	MedianLatency time.Duration
+}
+
+func FailureRate(_score ResolverScore) float64 {
+	if _score.Queries == 0 {
+		return 1
+	}
+	return float64(_score.Failures+_score.Empty) / float64(_score.Queries)
+}
+
+func Consistency(_score ResolverScore) float64 {
+	if _score.Compared == 0 {
+		return 0
+	}
+	return float64(_score.Compared-_score.Inconsistent) / float64(_score.Compared)
+}
+
+// _ipvs holds IP version of every host, so each family is asked for its own records
+func BenchmarkResolvers(_ctx context.Context, _resolvers []string, _hosts []string, _ipvs []int, _timeout int, _retries int, _skipVerify bool, _concurrency int) []ResolverScore {
+	answers := make([][][]string, len(_resolvers))
+	latencies := make([][]time.Duration, len(_resolvers))
+	scores := make([]ResolverScore, len(_resolvers))
+	for i, resolver := range _resolvers {
+		t, _ := ResolverTransport(resolver)
+		scores[i] = ResolverScore{
+			Resolver:  resolver,
+			Transport: t,
+		}
+		answers[i] = make([][]string, len(_hosts))
+		latencies[i] = make([]time.Duration, len(_hosts))
+	}
+
+	utils.RunWorkerPool(_concurrency, len(_resolvers)*len(_hosts), func(job int) {
+		defer supervisor.Guard()
+		r, h := job/len(_hosts), job%len(_hosts)
+		result, err := DnsLookupFresh(_ctx, _resolvers[r], _hosts[h], _ipvs[h], _timeout, _retries, _skipVerify)
+		if err != nil {
+			latencies[r][h] = -1
+			return
+		}
+		latencies[r][h] = result.Latency
+		answers[r][h] = ExtractAddresses(result)
+	})
+
+	for r := range _resolvers {
+		var ok []time.Duration
+		var total time.Duration
+		for h := range _hosts {
+			scores[r].Queries++
+			if latencies[r][h] < 0 {
+				scores[r].Failures++
+				continue
+			}
+			ok = append(ok, latencies[r][h])
+			total += latencies[r][h]
+			if len(answers[r][h]) == 0 {
+				scores[r].Empty++
+				continue
+			}
+			// an answer is consistent when it shares at least one address with any other resolver
+			var others []string
+			for o := range _resolvers {
+				if o != r {
+					others = append(others, answers[o][h]...)
+				}
+			}
+			if len(others) == 0 {
+				continue
+			}
+			scores[r].Compared++
+			consistent := false
+			for _, ip := range answers[r][h] {
+				if slices.Contains(others, ip) {
+					consistent = true
+					break
+				}
+			}
+			if !consistent {
+				scores[r].Inconsistent++
+			}
+		}
+		if len(ok) > 0 {
+			scores[r].AvgLatency = total / time.Duration(len(ok))
+			slices.Sort(ok)
+			scores[r].MedianLatency = ok[len(ok)/2]
+		}
+	}
+
+	sort.SliceStable(scores, func(i, j int) bool {
+		if FailureRate(scores[i]) != FailureRate(scores[j]) {
+			return FailureRate(scores[i]) < FailureRate(scores[j])
+		}
+		if Consistency(scores[i]) != Consistency(scores[j]) {
+			return Consistency(scores[i]) > Consistency(scores[j])
+		}
+		return scores[i].MedianLatency < scores[j].MedianLatency
+	})
+	return scores
+}
@@ -0,0 +1,132 @@
+package lookup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type cacheEntry struct {
+	Resolver string
+	Host     string
+	IPV      int
+	Stored   time.Time
+	Expires  time.Time
+	Result   DnsResult
+}
+
+var (
+	cache         = map[string]cacheEntry{}
+	cacheMutex    sync.Mutex
+	cacheFile     string
+	cacheMaxStale time.Duration
+	cacheRefresh  bool
+	cacheEnabled  bool
+)
+
+func OpenCache(_file string, _maxStaleSec int, _refresh bool) error {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	cacheFile = _file
+	cacheMaxStale = time.Duration(_maxStaleSec) * time.Second
+	cacheRefresh = _refresh
+	cacheEnabled = true
+
+	b, err := os.ReadFile(_file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("can't read a file '%s': %v", _file, err)
+	}
+	var entries []cacheEntry
+	err = json.Unmarshal(b, &entries)
+	if err != nil {
+		return fmt.Errorf("can't parse a file '%s': %v", _file, err)
+	}
+	for _, entry := range entries {
+		if time.Since(entry.Expires) > cacheMaxStale {
+			continue
+		}
+		cache[cacheKey(entry.Resolver, entry.Host, entry.IPV)] = entry
+	}
+	return nil
+}
+
+func SaveCache() error {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	if !cacheEnabled {
+		return nil
+	}
+	var entries []cacheEntry
+	for _, entry := range cache {
+		entries = append(entries, entry)
+	}
+	b, err := json.MarshalIndent(entries, "", "\t")
+	if err != nil {
+		return fmt.Errorf("can't marshal json: %v", err)
+	}
+	d := filepath.Dir(cacheFile)
+	if d != "." {
+		err = os.MkdirAll(d, 0755)
+		if err != nil {
+			return fmt.Errorf("can't create a folder '%s': %v", d, err)
+		}
+	}
+	err = os.WriteFile(cacheFile, b, 0644)
+	if err != nil {
+		return fmt.Errorf("can't write a file '%s': %v", cacheFile, err)
+	}
+	return nil
+}
+
+func cacheKey(_resolver string, _host string, _ipv int) string {
+	return fmt.Sprintf("%s|%s|%d", _resolver, _host, _ipv)
+}
+
+func cacheGet(_resolver string, _host string, _ipv int) (DnsResult, bool) {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	if !cacheEnabled || cacheRefresh {
+		return DnsResult{}, false
+	}
+	entry, ok := cache[cacheKey(_resolver, _host, _ipv)]
+	if !ok || time.Since(entry.Expires) > cacheMaxStale {
+		return DnsResult{}, false
+	}
+	r := entry.Result
+	r.FromCache = true
+	r.CacheAge = time.Since(entry.Stored)
+	return r, true
+}
+
+func cachePut(_resolver string, _host string, _ipv int, _result DnsResult) {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	if !cacheEnabled || len(ExtractAddresses(_result)) == 0 {
+		return
+	}
+	var ttl uint32
+	for i, record := range _result.Answers {
+		if i == 0 || record.TTL < ttl {
+			ttl = record.TTL
+		}
+	}
+	now := time.Now()
+	cache[cacheKey(_resolver, _host, _ipv)] = cacheEntry{
+		Resolver: _resolver,
+		Host:     _host,
+		IPV:      _ipv,
+		Stored:   now,
+		Expires:  now.Add(time.Duration(ttl) * time.Second),
+		Result:   _result,
+	}
+}
@@ -0,0 +1,185 @@
+package lookup
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	VerdictOK             = "ok"
+	VerdictStub           = "stub"
+	VerdictNXDomain       = "nxdomain"
+	VerdictEmpty          = "empty"
+	VerdictASNMismatch    = "asn-mismatch"
+	VerdictIPMismatch     = "ip-mismatch"
+	VerdictSystemFailed   = "system-failed"
+	VerdictNoReference    = "no-reference"
+	VerdictResolverFailed = "resolver-failed"
+)
+
+type Diagnosis struct {
+	Host          string
+	Verdict       string
+	SystemRcode   string
+	SystemIPs     []string
+	SystemASNs    []string
+	ResolverRcode string
+	ResolverIPs   []string
+	ResolverASNs  []string
+}
+
+var (
+	asnCache      = map[string][]string{}
+	asnCacheMutex sync.Mutex
+)
+
+func IsDNSBlocked(verdict string) bool {
+	switch verdict {
+	case VerdictStub, VerdictNXDomain, VerdictEmpty, VerdictASNMismatch, VerdictSystemFailed:
+		return true
+	}
+	return false
+}
+
+func DiagnoseHost(_ctx context.Context, _resolver string, _host string, _ipv int, _timeout int, _retries int, _skipVerify bool, _stubIPs []string) Diagnosis {
+	d := Diagnosis{
+		Host:    _host,
+		Verdict: VerdictOK,
+	}
+
+	qtype := dns.TypeA
+	if _ipv == 6 {
+		qtype = dns.TypeAAAA
+	}
+
+	resolverResp, err := exchange(_ctx, _resolver, _host, qtype, _timeout, _retries, _skipVerify)
+	if err != nil {
+		d.Verdict = VerdictResolverFailed
+		return d
+	}
+	d.ResolverRcode = resolverResp.RcodeName
+	d.ResolverIPs = ExtractAddresses(resolverResp)
+
+	// plain UDP/53 query through the resolver configured in the system
+	systemResp, err := exchange(_ctx, "", _host, qtype, _timeout, _retries, _skipVerify)
+	if err != nil {
+		if len(d.ResolverIPs) > 0 {
+			d.Verdict = VerdictSystemFailed
+		} else {
+			d.Verdict = VerdictNoReference
+		}
+		return d
+	}
+	d.SystemRcode = systemResp.RcodeName
+	d.SystemIPs = ExtractAddresses(systemResp)
+
+	for _, ip := range d.SystemIPs {
+		if isStubIP(ip, _stubIPs) && !slices.Contains(d.ResolverIPs, ip) {
+			d.Verdict = VerdictStub
+			return d
+		}
+	}
+	if len(d.ResolverIPs) == 0 {
+		d.Verdict = VerdictNoReference
+		return d
+	}
+	if systemResp.Rcode == dns.RcodeNameError {
+		d.Verdict = VerdictNXDomain
+		return d
+	}
+	if len(d.SystemIPs) == 0 {
+		d.Verdict = VerdictEmpty
+		return d
+	}
+	for _, ip := range d.SystemIPs {
+		if slices.Contains(d.ResolverIPs, ip) {
+			return d
+		}
+	}
+
+	// different addresses are fine as long as they belong to the same network, CDNs do that all the time
+	d.SystemASNs = lookupASNs(_ctx, _resolver, d.SystemIPs, _timeout, _retries, _skipVerify)
+	d.ResolverASNs = lookupASNs(_ctx, _resolver, d.ResolverIPs, _timeout, _retries, _skipVerify)
+	if len(d.SystemASNs) == 0 || len(d.ResolverASNs) == 0 {
+		d.Verdict = VerdictIPMismatch
+		return d
+	}
+	for _, asn := range d.SystemASNs {
+		if slices.Contains(d.ResolverASNs, asn) {
+			return d
+		}
+	}
+	d.Verdict = VerdictASNMismatch
+	return d
+}
+
+// only known stubs count, private addresses are normal for intranet and split-horizon names
+func isStubIP(ip string, stubIPs []string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, stubIP := range stubIPs {
+		if parsed.Equal(net.ParseIP(stubIP)) {
+			return true
+		}
+	}
+	return false
+}
+
+func lookupASNs(_ctx context.Context, _resolver string, _ips []string, _timeout int, _retries int, _skipVerify bool) []string {
+	var asns []string
+	for _, ip := range _ips {
+		for _, asn := range lookupASN(_ctx, _resolver, ip, _timeout, _retries, _skipVerify) {
+			if !slices.Contains(asns, asn) {
+				asns = append(asns, asn)
+			}
+		}
+	}
+	return asns
+}
+
+// uses Team Cymru IP-to-ASN mapping over DNS, so the query goes through the trusted resolver as well
+func lookupASN(_ctx context.Context, _resolver string, _ip string, _timeout int, _retries int, _skipVerify bool) []string {
+	asnCacheMutex.Lock()
+	cached, ok := asnCache[_ip]
+	asnCacheMutex.Unlock()
+	if ok {
+		return cached
+	}
+
+	reversed, err := dns.ReverseAddr(_ip)
+	if err != nil {
+		return nil
+	}
+	var name string
+	if strings.HasSuffix(reversed, ".in-addr.arpa.") {
+		name = strings.TrimSuffix(reversed, "in-addr.arpa.") + "origin.asn.cymru.com."
+	} else {
+		name = strings.TrimSuffix(reversed, "ip6.arpa.") + "origin6.asn.cymru.com."
+	}
+
+	resp, err := exchange(_ctx, _resolver, name, dns.TypeTXT, _timeout, _retries, _skipVerify)
+	if err != nil {
+		return nil
+	}
+	var asns []string
+	for _, record := range resp.Answers {
+		if record.Type != "TXT" {
+			continue
+		}
+		// "13335 | 104.16.0.0/13 | US | arin | 2014-03-28"
+		fields := strings.Split(record.Value, "|")
+		asns = append(asns, strings.Fields(fields[0])...)
+	}
+
+	asnCacheMutex.Lock()
+	asnCache[_ip] = asns
+	asnCacheMutex.Unlock()
+	return asns
+}
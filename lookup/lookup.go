@@ -1,97 +1,359 @@
-package lookup
-
-import (
-	"encoding/json"
-	"fmt"
-	"time"
-
-	"github.com/AdguardTeam/dnsproxy/upstream"
-	"github.com/AdguardTeam/golibs/log"
-	"github.com/AdguardTeam/golibs/netutil/sysresolv"
-	"github.com/miekg/dns"
-)
-
-type DnsResponse struct {
-	Response bool
-	Zero     bool
-	Answer   []DnsAnswer
-}
-
-type DnsAnswer struct {
-	A    string
-	AAAA string
-}
-
-func DnsLookup(_resolver string, _addrToResolve string, _ipv int, _timeout int, _retries int, _skipVerify bool) (DnsResponse, error) {
-	var c DnsResponse
-
-	o := &upstream.Options{
-		Timeout:            time.Duration(_timeout) * time.Second,
-		InsecureSkipVerify: _skipVerify,
-		HTTPVersions:       []upstream.HTTPVersion{upstream.HTTPVersion2, upstream.HTTPVersion11},
-	}
-
-	if _resolver == "" {
-		systemResolvers, err := sysresolv.NewSystemResolvers(nil, 53)
-		if err != nil {
-			return c, fmt.Errorf("can't get system resolvers: %v", err)
-		}
-		_resolver = systemResolvers.Addrs()[0].String()
-	}
-
-	u, err := upstream.AddressToUpstream(_resolver, o)
-	if err != nil {
-		return c, fmt.Errorf("can't create an upstream: %v", err)
-	}
-	defer u.Close()
-
-	// rr, ok := dns.StringToType[_rrType]
-	// if !ok {
-	// 	return c, fmt.Errorf("invalid RRTYPE '%s'", _rrType)
-	// }
-
-	var q = dns.Question{
-		Name:   dns.Fqdn(_addrToResolve),
-		Qclass: dns.ClassINET,
-	}
-	q.Qtype = dns.TypeA
-	if _ipv == 6 {
-		q.Qtype = dns.TypeAAAA
-	}
-
-	req := &dns.Msg{}
-	req.Id = dns.Id()
-	req.RecursionDesired = true
-	req.Question = []dns.Question{q}
-
-	retries := _retries
-	var resp *dns.Msg
-	for retries >= 0 {
-		resp, err = u.Exchange(req)
-		if err == nil {
-			u.Close()
-			break
-		} else {
-			log.Printf("Can't resolve '%s' (attempts left %d): %v", _addrToResolve, retries, err)
-			if retries == 0 {
-				u.Close()
-				log.Println("No attempts left")
-				return c, fmt.Errorf("can't resolve")
-			}
-			retries--
-		}
-	}
-
-	var b []byte
-	b, err = json.Marshal(resp)
-	if err != nil {
-		return c, fmt.Errorf("can't marshal json: %v", err)
-	}
-
-	err = json.Unmarshal(b, &c)
-	if err != nil {
-		return c, fmt.Errorf("can't unmarshal json: %v", err)
-	}
-
-	return c, nil
-}
+package lookup
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AdguardTeam/dnsproxy/upstream"
+	"github.com/AdguardTeam/golibs/log"
+	"github.com/AdguardTeam/golibs/netutil/sysresolv"
+	"github.com/miekg/dns"
+)
+
+const (
+	TransportSystem   = "System"
+	TransportPlain    = "Plain"
+	TransportDoH      = "DoH"
+	TransportDoH3     = "DoH3"
+	TransportDoT      = "DoT"
+	TransportDoQ      = "DoQ"
+	TransportDNSCrypt = "DNSCrypt"
+)
+
+type DnsResult struct {
+	Resolver       string
+	Name           string
+	Qtype          string
+	Rcode          int
+	RcodeName      string
+	Answers        []DnsRecord
+	EDNSSize       uint16
+	ExtendedErrors []string
+	Latency        time.Duration
+	Attempts       int
+	FromCache      bool
+	CacheAge       time.Duration
+}
+
+type DnsRecord struct {
+	Name  string
+	Type  string
+	TTL   uint32
+	Value string
+}
+
+func DnsLookup(_ctx context.Context, _resolver string, _addrToResolve string, _ipv int, _timeout int, _retries int, _skipVerify bool) (DnsResult, error) {
+	if r, ok := cacheGet(_resolver, _addrToResolve, _ipv); ok {
+		return r, nil
+	}
+	r, err := DnsLookupFresh(_ctx, _resolver, _addrToResolve, _ipv, _timeout, _retries, _skipVerify)
+	if err == nil {
+		cachePut(_resolver, _addrToResolve, _ipv, r)
+	}
+	return r, err
+}
+
+func DnsLookupFresh(_ctx context.Context, _resolver string, _addrToResolve string, _ipv int, _timeout int, _retries int, _skipVerify bool) (DnsResult, error) {
+	qtype := dns.TypeA
+	if _ipv == 6 {
+		qtype = dns.TypeAAAA
+	}
+	return exchange(_ctx, _resolver, _addrToResolve, qtype, _timeout, _retries, _skipVerify)
+}
+
+func ExtractAddresses(_result DnsResult) []string {
+	var ips []string
+	for _, record := range _result.Answers {
+		if record.Type != "A" && record.Type != "AAAA" {
+			continue
+		}
+		if !slices.Contains(ips, record.Value) {
+			ips = append(ips, record.Value)
+		}
+	}
+	return ips
+}
+
+func DescribeResult(_result DnsResult) string {
+	if _result.Rcode != dns.RcodeSuccess {
+		d := _result.RcodeName
+		if len(_result.ExtendedErrors) > 0 {
+			d = fmt.Sprintf("%s %s", d, _result.ExtendedErrors)
+		}
+		return d
+	}
+	if len(_result.Answers) == 0 {
+		return "empty answer"
+	}
+	if len(ExtractAddresses(_result)) == 0 {
+		var chain []string
+		for _, record := range _result.Answers {
+			chain = append(chain, fmt.Sprintf("%s %s", record.Type, record.Value))
+		}
+		return fmt.Sprintf("no %s records in answer %s", _result.Qtype, chain)
+	}
+	return "ok"
+}
+
+func DescribeChain(_result DnsResult) string {
+	var chain []string
+	for _, record := range _result.Answers {
+		chain = append(chain, fmt.Sprintf("%s %s %s (TTL %d)", record.Name, record.Type, record.Value, record.TTL))
+	}
+	return strings.Join(chain, " -> ")
+}
+
+var (
+	upstreams      = map[string]upstream.Upstream{}
+	upstreamsMutex sync.Mutex
+	systemResolver string
+
+	bootstrapAddrs     []string
+	bootstrapResolver  upstream.Resolver
+	bootstrapUpstreams []upstream.Upstream
+)
+
+func ResolverTransport(_resolver string) (string, error) {
+	if _resolver == "" {
+		return TransportSystem, nil
+	}
+	if !strings.Contains(_resolver, "://") {
+		host := _resolver
+		if h, _, err := net.SplitHostPort(_resolver); err == nil {
+			host = h
+		}
+		if net.ParseIP(strings.Trim(host, "[]")) == nil {
+			return "", fmt.Errorf("plain resolver address must be an IP, optionally with a port")
+		}
+		return TransportPlain, nil
+	}
+	u, err := url.Parse(_resolver)
+	if err != nil {
+		return "", fmt.Errorf("can't parse resolver address: %v", err)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("resolver address has no host")
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "https":
+		return TransportDoH, nil
+	case "h3":
+		return TransportDoH3, nil
+	case "tls":
+		return TransportDoT, nil
+	case "quic":
+		return TransportDoQ, nil
+	case "sdns":
+		return TransportDNSCrypt, nil
+	case "udp", "tcp":
+		return TransportPlain, nil
+	}
+	return "", fmt.Errorf("unsupported scheme '%s': expected https, h3, tls, quic, sdns, udp, tcp or a plain IP", u.Scheme)
+}
+
+func DescribeResolver(_resolver string) string {
+	t, err := ResolverTransport(_resolver)
+	if err != nil {
+		t = "invalid"
+	}
+	if _resolver == "" {
+		return t
+	}
+	return fmt.Sprintf("%s (%s)", _resolver, t)
+}
+
+func SetBootstrap(_addrs []string) {
+	upstreamsMutex.Lock()
+	defer upstreamsMutex.Unlock()
+
+	bootstrapAddrs = _addrs
+	bootstrapResolver = nil
+}
+
+func getBootstrap(_timeout int) upstream.Resolver {
+	if len(bootstrapAddrs) == 0 {
+		return nil
+	}
+	if bootstrapResolver != nil {
+		return bootstrapResolver
+	}
+	o := &upstream.Options{
+		Timeout: time.Duration(_timeout) * time.Second,
+	}
+	var resolvers upstream.ParallelResolver
+	for _, addr := range bootstrapAddrs {
+		r, err := upstream.NewUpstreamResolver(addr, o)
+		if err != nil {
+			log.Printf("Can't use '%s' as a bootstrap: %v", addr, err)
+			continue
+		}
+		resolvers = append(resolvers, r)
+		bootstrapUpstreams = append(bootstrapUpstreams, r.Upstream)
+	}
+	if len(resolvers) == 0 {
+		return nil
+	}
+	bootstrapResolver = resolvers
+	return bootstrapResolver
+}
+
+func getUpstream(_resolver string, _timeout int, _skipVerify bool) (upstream.Upstream, error) {
+	upstreamsMutex.Lock()
+	defer upstreamsMutex.Unlock()
+
+	if _resolver == "" {
+		if systemResolver == "" {
+			systemResolvers, err := sysresolv.NewSystemResolvers(nil, 53)
+			if err != nil {
+				return nil, fmt.Errorf("can't get system resolvers: %v", err)
+			}
+			systemResolver = systemResolvers.Addrs()[0].String()
+		}
+		_resolver = systemResolver
+	}
+
+	key := fmt.Sprintf("%s|%d|%t", _resolver, _timeout, _skipVerify)
+	if u, ok := upstreams[key]; ok {
+		return u, nil
+	}
+
+	o := &upstream.Options{
+		Timeout:            time.Duration(_timeout) * time.Second,
+		InsecureSkipVerify: _skipVerify,
+		HTTPVersions:       []upstream.HTTPVersion{upstream.HTTPVersion2, upstream.HTTPVersion11},
+		Bootstrap:          getBootstrap(_timeout),
+	}
+
+	u, err := upstream.AddressToUpstream(_resolver, o)
+	if err != nil {
+		return nil, fmt.Errorf("can't create an upstream: %v", err)
+	}
+	upstreams[key] = u
+	return u, nil
+}
+
+func CloseUpstreams() {
+	upstreamsMutex.Lock()
+	defer upstreamsMutex.Unlock()
+
+	for key, u := range upstreams {
+		u.Close()
+		delete(upstreams, key)
+	}
+	for _, u := range bootstrapUpstreams {
+		u.Close()
+	}
+	bootstrapUpstreams = nil
+	bootstrapResolver = nil
+}
+
+func exchange(_ctx context.Context, _resolver string, _name string, _qtype uint16, _timeout int, _retries int, _skipVerify bool) (DnsResult, error) {
+	r, _, err := exchangeMsg(_ctx, _resolver, _name, _qtype, _timeout, _retries, _skipVerify)
+	return r, err
+}
+
+func exchangeMsg(_ctx context.Context, _resolver string, _name string, _qtype uint16, _timeout int, _retries int, _skipVerify bool) (DnsResult, *dns.Msg, error) {
+	r := DnsResult{
+		Resolver: DescribeResolver(_resolver),
+		Name:     _name,
+		Qtype:    dns.TypeToString[_qtype],
+		Rcode:    -1,
+	}
+
+	u, err := getUpstream(_resolver, _timeout, _skipVerify)
+	if err != nil {
+		return r, nil, err
+	}
+
+	var q = dns.Question{
+		Name:   dns.Fqdn(_name),
+		Qtype:  _qtype,
+		Qclass: dns.ClassINET,
+	}
+
+	req := &dns.Msg{}
+	req.Id = dns.Id()
+	req.RecursionDesired = true
+	req.Question = []dns.Question{q}
+	req.SetEdns0(dns.DefaultMsgSize, false)
+
+	retries := _retries
+	var resp *dns.Msg
+	for retries >= 0 {
+		r.Attempts++
+		start := time.Now()
+		resp, err = exchangeContext(_ctx, u, req)
+		r.Latency = time.Since(start)
+		if err == nil {
+			break
+		} else if _ctx.Err() != nil {
+			return r, nil, fmt.Errorf("lookup of '%s' was cancelled: %v", _name, err)
+		} else {
+			log.Printf("Can't resolve '%s' (attempts left %d): %v", _name, retries, err)
+			if retries == 0 {
+				log.Println("No attempts left")
+				return r, nil, fmt.Errorf("can't resolve '%s' via '%s' after %d attempt(s): %v", _name, r.Resolver, r.Attempts, err)
+			}
+			retries--
+		}
+	}
+
+	r.Rcode = resp.Rcode
+	r.RcodeName = dns.RcodeToString[resp.Rcode]
+	for _, rr := range resp.Answer {
+		h := rr.Header()
+		record := DnsRecord{
+			Name: strings.TrimSuffix(h.Name, "."),
+			Type: dns.TypeToString[h.Rrtype],
+			TTL:  h.Ttl,
+		}
+		switch v := rr.(type) {
+		case *dns.A:
+			record.Value = v.A.String()
+		case *dns.AAAA:
+			record.Value = v.AAAA.String()
+		case *dns.CNAME:
+			record.Value = strings.TrimSuffix(v.Target, ".")
+		case *dns.TXT:
+			record.Value = strings.Join(v.Txt, " ")
+		default:
+			record.Value = strings.TrimSpace(strings.TrimPrefix(rr.String(), h.String()))
+		}
+		r.Answers = append(r.Answers, record)
+	}
+	if opt := resp.IsEdns0(); opt != nil {
+		r.EDNSSize = opt.UDPSize()
+		for _, o := range opt.Option {
+			if ede, ok := o.(*dns.EDNS0_EDE); ok {
+				r.ExtendedErrors = append(r.ExtendedErrors, ede.String())
+			}
+		}
+	}
+
+	return r, resp, nil
+}
+
+// upstreams know nothing about contexts, so a cancelled exchange is abandoned and left to time out on its own
+func exchangeContext(_ctx context.Context, _u upstream.Upstream, _req *dns.Msg) (*dns.Msg, error) {
+	type reply struct {
+		resp *dns.Msg
+		err  error
+	}
+	ch := make(chan reply, 1)
+	go func() {
+		resp, err := _u.Exchange(_req)
+		ch <- reply{resp, err}
+	}()
+	select {
+	case r := <-ch:
+		return r.resp, r.err
+	case <-_ctx.Done():
+		return nil, _ctx.Err()
+	}
+}
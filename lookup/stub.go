@@ -0,0 +1,169 @@
+package lookup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AdguardTeam/dnsproxy/proxy"
+	"github.com/AdguardTeam/dnsproxy/upstream"
+	"github.com/miekg/dns"
+)
+
+type StubQuery struct {
+	Name   string
+	Qtype  string
+	Rcode  string
+	Answer []string
+	Err    string
+}
+
+var (
+	stub         *proxy.Proxy
+	stubUpstream upstream.Upstream
+	stubTag      string
+	stubLog      = map[string][]StubQuery{}
+	stubMutex    sync.Mutex
+)
+
+func StartStub(_listenAddr string, _resolver string, _timeout int, _skipVerify bool) error {
+	if _resolver == "" {
+		return fmt.Errorf("local DNS requires a custom resolver to forward to")
+	}
+	udpAddr, err := net.ResolveUDPAddr("udp", _listenAddr)
+	if err != nil {
+		return fmt.Errorf("can't parse listen address '%s': %v", _listenAddr, err)
+	}
+	tcpAddr, err := net.ResolveTCPAddr("tcp", _listenAddr)
+	if err != nil {
+		return fmt.Errorf("can't parse listen address '%s': %v", _listenAddr, err)
+	}
+
+	upstreamsMutex.Lock()
+	bootstrap := getBootstrap(_timeout)
+	upstreamsMutex.Unlock()
+	if bootstrap == nil {
+		bootstrap = pinResolverHost(_resolver)
+	}
+
+	o := &upstream.Options{
+		Timeout:            time.Duration(_timeout) * time.Second,
+		InsecureSkipVerify: _skipVerify,
+		HTTPVersions:       []upstream.HTTPVersion{upstream.HTTPVersion2, upstream.HTTPVersion11},
+		Bootstrap:          bootstrap,
+	}
+	u, err := upstream.AddressToUpstream(_resolver, o)
+	if err != nil {
+		return fmt.Errorf("can't create an upstream: %v", err)
+	}
+
+	p, err := proxy.New(&proxy.Config{
+		Logger:          slog.New(slog.NewTextHandler(io.Discard, nil)),
+		UDPListenAddr:   []*net.UDPAddr{udpAddr},
+		TCPListenAddr:   []*net.TCPAddr{tcpAddr},
+		UpstreamConfig:  &proxy.UpstreamConfig{Upstreams: []upstream.Upstream{u}},
+		ResponseHandler: logStubQuery,
+	})
+	if err != nil {
+		u.Close()
+		return fmt.Errorf("can't create a local DNS: %v", err)
+	}
+	err = p.Start(context.Background())
+	if err != nil {
+		u.Close()
+		return fmt.Errorf("can't start a local DNS at '%s': %v", _listenAddr, err)
+	}
+
+	stubMutex.Lock()
+	stub = p
+	stubUpstream = u
+	stubMutex.Unlock()
+	return nil
+}
+
+// system DNS may be pointed to the stub itself, so the resolver host is looked up once, while it's still the old one
+func pinResolverHost(_resolver string) upstream.Resolver {
+	t, _ := ResolverTransport(_resolver)
+	if t != TransportDoH && t != TransportDoH3 && t != TransportDoT && t != TransportDoQ {
+		return nil
+	}
+	u, err := url.Parse(_resolver)
+	if err != nil || net.ParseIP(u.Hostname()) != nil {
+		return nil
+	}
+	ips, err := net.DefaultResolver.LookupNetIP(context.Background(), "ip", u.Hostname())
+	if err != nil || len(ips) == 0 {
+		log.Printf("Can't look up '%s' for local DNS, leaving it to the system: %v\n", u.Hostname(), err)
+		return nil
+	}
+	return upstream.StaticResolver(ips)
+}
+
+func StopStub() error {
+	stubMutex.Lock()
+	p, u := stub, stubUpstream
+	stub, stubUpstream = nil, nil
+	stubMutex.Unlock()
+
+	if p == nil {
+		return nil
+	}
+	defer u.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := p.Shutdown(ctx)
+	if err != nil {
+		return fmt.Errorf("can't stop a local DNS: %v", err)
+	}
+	return nil
+}
+
+func SetStubTag(_tag string) {
+	stubMutex.Lock()
+	defer stubMutex.Unlock()
+
+	stubTag = _tag
+}
+
+func StubQueries(_tag string) []StubQuery {
+	stubMutex.Lock()
+	defer stubMutex.Unlock()
+
+	return stubLog[_tag]
+}
+
+func logStubQuery(dctx *proxy.DNSContext, err error) {
+	if dctx.Req == nil || len(dctx.Req.Question) == 0 {
+		return
+	}
+	q := StubQuery{
+		Name:  strings.TrimSuffix(dctx.Req.Question[0].Name, "."),
+		Qtype: dns.TypeToString[dctx.Req.Question[0].Qtype],
+	}
+	if err != nil {
+		q.Err = err.Error()
+	}
+	if dctx.Res != nil {
+		q.Rcode = dns.RcodeToString[dctx.Res.Rcode]
+		for _, rr := range dctx.Res.Answer {
+			switch v := rr.(type) {
+			case *dns.A:
+				q.Answer = append(q.Answer, v.A.String())
+			case *dns.AAAA:
+				q.Answer = append(q.Answer, v.AAAA.String())
+			}
+		}
+	}
+
+	stubMutex.Lock()
+	defer stubMutex.Unlock()
+
+	stubLog[stubTag] = append(stubLog[stubTag], q)
+}
@@ -0,0 +1,105 @@
+package lookup
+
+import (
+	"context"
+	"encoding/base64"
+	"slices"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+type HTTPSRecord struct {
+	Priority  uint16
+	Target    string
+	ALPN      []string
+	ECHConfig string
+	IPv4Hints []string
+	IPv6Hints []string
+}
+
+type HTTPSInfo struct {
+	Host      string
+	RcodeName string
+	Records   []HTTPSRecord
+	ALPN      []string
+	ECH       bool
+	H3        bool
+	Hints     []string
+}
+
+func LookupHTTPS(_ctx context.Context, _resolver string, _host string, _timeout int, _retries int, _skipVerify bool) (HTTPSInfo, error) {
+	info := HTTPSInfo{
+		Host: _host,
+	}
+	name := _host
+	// alias mode (priority 0) only points somewhere else, follow it once
+	for hop := 0; hop < 2; hop++ {
+		r, resp, err := exchangeMsg(_ctx, _resolver, name, dns.TypeHTTPS, _timeout, _retries, _skipVerify)
+		if err != nil {
+			return info, err
+		}
+		info.RcodeName = r.RcodeName
+		alias := ""
+		for _, rr := range resp.Answer {
+			v, ok := rr.(*dns.HTTPS)
+			if !ok {
+				continue
+			}
+			if v.Priority == 0 {
+				alias = strings.TrimSuffix(v.Target, ".")
+				continue
+			}
+			info.Records = append(info.Records, parseSVCB(v.SVCB))
+		}
+		if len(info.Records) > 0 || alias == "" || alias == name {
+			break
+		}
+		name = alias
+	}
+
+	for _, record := range info.Records {
+		for _, alpn := range record.ALPN {
+			if !slices.Contains(info.ALPN, alpn) {
+				info.ALPN = append(info.ALPN, alpn)
+			}
+			// draft versions are advertised as "h3-29" and the like
+			if alpn == "h3" || strings.HasPrefix(alpn, "h3-") {
+				info.H3 = true
+			}
+		}
+		if record.ECHConfig != "" {
+			info.ECH = true
+		}
+		for _, ip := range append(record.IPv4Hints, record.IPv6Hints...) {
+			if !slices.Contains(info.Hints, ip) {
+				info.Hints = append(info.Hints, ip)
+			}
+		}
+	}
+	return info, nil
+}
+
+func parseSVCB(_svcb dns.SVCB) HTTPSRecord {
+	record := HTTPSRecord{
+		Priority: _svcb.Priority,
+		Target:   strings.TrimSuffix(_svcb.Target, "."),
+	}
+	for _, kv := range _svcb.Value {
+		switch v := kv.(type) {
+		case *dns.SVCBAlpn:
+			record.ALPN = append(record.ALPN, v.Alpn...)
+		case *dns.SVCBECHConfig:
+			record.ECHConfig = base64.StdEncoding.EncodeToString(v.ECH)
+		case *dns.SVCBIPv4Hint:
+			for _, ip := range v.Hint {
+				record.IPv4Hints = append(record.IPv4Hints, ip.String())
+			}
+		case *dns.SVCBIPv6Hint:
+			for _, ip := range v.Hint {
+				record.IPv6Hints = append(record.IPv6Hints, ip.String())
+			}
+		}
+	}
+	return record
+}
@@ -1,184 +1,610 @@
-package requestscurl
-
-import (
-	"fmt"
-	"goodcheckgogo/checklist"
-	"goodcheckgogo/options"
-	"goodcheckgogo/strategy"
-	"goodcheckgogo/utils"
-	"log"
-	"net/http"
-	"os/exec"
-	"strconv"
-	"strings"
-	"syscall"
-)
-
-func CheckConnectivityCurl() (bool, error) {
-
-	keys := options.MyOptions.Curl.BasicKeys
-	keys = append(keys, fmt.Sprintf("-m %d", options.MyOptions.ConnTimeout.Value))
-	if strategy.IPV == 6 {
-		keys = append(keys, "-6")
-	} else {
-		keys = append(keys, "-4")
-	}
-	// if strategy.Protocol == "UDP" {
-	// 	keys = append(keys, "--http3-only")
-	// }
-	if options.MyOptions.SkipCertVerify.Value {
-		keys = append(keys, "--insecure")
-	}
-	keys = append(keys, `-w "%{response_code}"`, "-o NUL", options.MyOptions.NetConnTestURL.Value)
-
-	if !options.MyOptions.SkipCertVerify.Value {
-		log.Printf("Making normal request to '%s' (Curl)\n", options.MyOptions.NetConnTestURL.Value)
-	} else {
-		log.Printf("Making insecure request to '%s' (Curl)\n", options.MyOptions.NetConnTestURL.Value)
-	}
-
-	cmd := exec.Command(options.MyOptions.Curl.ExecutableFullPath)
-	cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: utils.PrintStringArray(keys)}
-
-	result, _ := cmd.Output()
-	if len(result) == 0 {
-		return false, fmt.Errorf("no response to read")
-	}
-
-	codeN, err := strconv.Atoi(string(result))
-	if err != nil {
-		return false, fmt.Errorf("can't convert response '%s' to integer: %v", result, err)
-	}
-
-	if codeN == 0 {
-		log.Println("No connection: response code 0")
-		return false, nil
-	} else {
-		log.Printf("Connection seems ok; response code: %d %s\n", codeN, http.StatusText(codeN))
-		return true, nil
-	}
-}
-
-func DnsLookupCurl(_resolver string, _addrToResolve string) string {
-	_addrToResolve = utils.InsensitiveReplace(_addrToResolve, "https://", "")
-
-	keys := options.MyOptions.Curl.BasicKeys
-	//keys = append(keys, fmt.Sprintf("-m %d", options.MyOptions.ConnTimeout.Value))
-	keys = append(keys, "-m 1")
-	if strategy.IPV == 6 {
-		keys = append(keys, "-6")
-	} else {
-		keys = append(keys, "-4")
-	}
-	if options.MyOptions.SkipCertVerify.Value {
-		keys = append(keys, "--insecure")
-	}
-	if _resolver == "" {
-		keys = append(keys, `-w "%{remote_ip}"`, "-o NUL", _addrToResolve)
-	} else {
-		keys = append(keys, `-w "%{remote_ip}"`, fmt.Sprintf("--doh-url %s", _resolver), "-Z", "-o NUL", _addrToResolve, "-o NUL", "0.0.0.0")
-	}
-
-	cmd := exec.Command(options.MyOptions.Curl.ExecutableFullPath)
-	cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: utils.PrintStringArray(keys)}
-
-	result, _ := cmd.Output()
-	if len(result) == 0 {
-		return ""
-	}
-	return string(result)
-}
-
-func ExtractClusterCurl(mappingURL string) string {
-	keys := options.MyOptions.Curl.BasicKeys
-	keys = append(keys, fmt.Sprintf("-m %d", options.MyOptions.ConnTimeout.Value))
-	if options.MyOptions.SkipCertVerify.Value {
-		keys = append(keys, "--insecure")
-	}
-	keys = append(keys, "-4", mappingURL)
-
-	cmd := exec.Command(options.MyOptions.Curl.ExecutableFullPath)
-	cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: utils.PrintStringArray(keys)}
-
-	result, _ := cmd.Output()
-	if len(result) == 0 {
-		return ""
-	}
-	s := strings.Split(string(result), " ")[2]
-	return s
-}
-
-func FormRequestsKeys(_resolver string, addresses []checklist.Website) []string {
-	keys := options.MyOptions.Curl.BasicKeys
-	keys = append(keys, fmt.Sprintf("-m %d", options.MyOptions.ConnTimeout.Value))
-	if strategy.IPV == 6 {
-		keys = append(keys, "-6")
-	} else {
-		keys = append(keys, "-4")
-	}
-	if strategy.Proxy != "noproxy" {
-		keys = append(keys, fmt.Sprintf("--proxy %s", strategy.Proxy))
-	}
-	if strategy.Protocol == "UDP" {
-		keys = append(keys, "--http3-only")
-	}
-	if options.MyOptions.SkipCertVerify.Value {
-		keys = append(keys, "--insecure")
-	}
-	// if _resolver != "" {
-	// 	keys = append(keys, fmt.Sprintf("--doh-url %s", _resolver))
-	// }
-	keys = append(keys, `-w "%{urlnum}$%{response_code}@"`)
-	//keys = append(keys, `-w "%{urlnum}$%{response_code}$%{errormsg}@"`)
-	if len(addresses) > 1 || _resolver != "" {
-		keys = append(keys, "-Z", "--parallel-immediate", "--parallel-max 200")
-	}
-	for _, addr := range addresses {
-		if strategy.Proxy == "noproxy" {
-			keys = append(keys, addr.Address, "-o NUL", fmt.Sprintf("--resolve %s:443:%s", utils.InsensitiveReplace(addr.Address, "https://", ""), addr.IP))
-		} else {
-			keys = append(keys, addr.Address, "-o NUL")
-		}
-	}
-	// if _resolver != "" && len(addresses) < 2 {
-	// 	keys = append(keys, "0.0.0.0", "-o NUL")
-	// }
-	return keys
-}
-
-func SendRequestsAndParse(keys []string, addresses *[]checklist.Website) error {
-	cmd := exec.Command(options.MyOptions.Curl.ExecutableFullPath)
-	cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: utils.PrintStringArray(keys)}
-
-	result, _ := cmd.Output()
-	if len(result) == 0 {
-		return fmt.Errorf("curl returned no results")
-	}
-
-	resultString := string(result)
-	//log.Println("resultString:", resultString)
-	resultLines := strings.Split(resultString, "@")
-	for _, line := range resultLines {
-		if line == "$" || line == "" {
-			break
-		}
-		v := strings.Split(line, "$")
-		index, err := strconv.Atoi(v[0])
-		if err != nil {
-			return fmt.Errorf("can't convert urlnum '%s' to integer: %v", v[0], err)
-		}
-		if index >= len((*addresses)) {
-			continue
-		}
-		code, err := strconv.Atoi(v[1])
-		if err != nil {
-			return fmt.Errorf("can't convert response code '%s' to integer: %v", v[1], err)
-		}
-		(*addresses)[index].LastResponseCode = code
-		if code != 0 {
-			(*addresses)[index].HasSuccesses = true
-		}
-	}
-	log.Println("Responses was received and parsed")
-	return nil
-}
+package requestscurl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goodcheckgogo/blockpage"
+	"goodcheckgogo/checklist"
+	"goodcheckgogo/options"
+	"goodcheckgogo/strategy"
+	"goodcheckgogo/supervisor"
+	"goodcheckgogo/tlsprofile"
+	"goodcheckgogo/utils"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// a copy, so appending to it never touches the option
+func basicKeys() []string {
+	return slices.Clone(options.MyOptions.Curl.BasicKeys)
+}
+
+func CheckConnectivityCurl(ipv int) (bool, error) {
+
+	keys := basicKeys()
+	keys = append(keys, "-m", strconv.Itoa(options.MyOptions.ConnTimeout.Value))
+	if ipv == 6 {
+		keys = append(keys, "-6")
+	} else {
+		keys = append(keys, "-4")
+	}
+	// if strategy.Protocol == "UDP" {
+	// 	keys = append(keys, "--http3-only")
+	// }
+	if options.MyOptions.SkipCertVerify.Value {
+		keys = append(keys, "--insecure")
+	}
+	keys = append(keys, "-w", "%{response_code}", "-o", os.DevNull, options.MyOptions.NetConnTestURL.Value)
+
+	if !options.MyOptions.SkipCertVerify.Value {
+		log.Printf("Making normal request to '%s' (Curl)\n", options.MyOptions.NetConnTestURL.Value)
+	} else {
+		log.Printf("Making insecure request to '%s' (Curl)\n", options.MyOptions.NetConnTestURL.Value)
+	}
+
+	cmd := exec.Command(options.MyOptions.Curl.ExecutableFullPath, keys...)
+
+	result, _ := cmd.Output()
+	if len(result) == 0 {
+		return false, fmt.Errorf("no response to read")
+	}
+
+	codeN, err := strconv.Atoi(string(result))
+	if err != nil {
+		return false, fmt.Errorf("can't convert response '%s' to integer: %v", result, err)
+	}
+
+	if codeN == 0 {
+		log.Println("No connection: response code 0")
+		return false, nil
+	} else {
+		log.Printf("Connection seems ok; response code: %d %s\n", codeN, http.StatusText(codeN))
+		return true, nil
+	}
+}
+
+func ExtractClusterCurl(mappingURL string) string {
+	keys := basicKeys()
+	keys = append(keys, "-m", strconv.Itoa(options.MyOptions.ConnTimeout.Value))
+	if options.MyOptions.SkipCertVerify.Value {
+		keys = append(keys, "--insecure")
+	}
+	keys = append(keys, "-4", mappingURL)
+
+	cmd := exec.Command(options.MyOptions.Curl.ExecutableFullPath, keys...)
+
+	result, _ := cmd.Output()
+	if len(result) == 0 {
+		return ""
+	}
+	s := strings.Split(string(result), " ")[2]
+	return s
+}
+
+// '%{json}' has 'exitcode' and 'errormsg' since curl 7.75.0; older ones get the rest of fields in a '$'-separated line
+var jsonWriteOut = false
+
+// old curl only has the process exit code, which can't be told apart between parallel transfers
+const exitUnknown = -1
+
+// fields of curl write-out that are used, named as in '%{json}'
+type writeOut struct {
+	ResponseCode      int     `json:"response_code"`
+	Exitcode          int     `json:"exitcode"`
+	Errormsg          string  `json:"errormsg"`
+	TimeConnect       float64 `json:"time_connect"`
+	TimeAppconnect    float64 `json:"time_appconnect"`
+	TimeStarttransfer float64 `json:"time_starttransfer"`
+	SizeDownload      int64   `json:"size_download"`
+	SpeedDownload     float64 `json:"speed_download"`
+	RemoteIP          string  `json:"remote_ip"`
+	RedirectURL       string  `json:"redirect_url"`
+	HTTPVersion       string  `json:"http_version"`
+	URLEffective      string  `json:"url_effective"`
+	// curl fills it with '--insecure' too, so a substituted certificate shows up even when the transfer goes on
+	SSLVerifyResult int `json:"ssl_verify_result"`
+}
+
+var alpnNames = map[string]string{
+	"1":   "http/1.0",
+	"1.0": "http/1.0",
+	"1.1": "http/1.1",
+	"2":   "h2",
+	"3":   "h3",
+}
+
+// SetWriteOut picks write-out format by the version of curl
+func SetWriteOut() error {
+	cmd := exec.Command(options.MyOptions.Curl.ExecutableFullPath, "-V")
+	result, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("can't get curl version: %v", err)
+	}
+	fields := strings.Fields(string(result))
+	if len(fields) < 2 {
+		return fmt.Errorf("can't parse curl version from '%s'", result)
+	}
+	var major, minor int
+	_, err = fmt.Sscanf(fields[1], "%d.%d", &major, &minor)
+	if err != nil {
+		return fmt.Errorf("can't parse curl version '%s': %v", fields[1], err)
+	}
+	jsonWriteOut = major > 7 || (major == 7 && minor >= 75)
+	if jsonWriteOut {
+		log.Printf("Curl version: %s, results are read as JSON\n", fields[1])
+	} else {
+		log.Printf("Curl version: %s, too old for JSON results, falling back to plain ones\n", fields[1])
+	}
+	return nil
+}
+
+// transfer number goes first, so results can be matched with targets whatever order they finish in
+func writeOutKey(n int) string {
+	if jsonWriteOut {
+		return fmt.Sprintf(`%d$%%{json}\n`, n)
+	}
+	// only variables older curl knows; effective URL goes last, so it may contain '$'
+	return fmt.Sprintf(`%d$%%{response_code}$%%{time_connect}$%%{time_appconnect}$%%{time_starttransfer}$%%{redirect_url}$%%{remote_ip}$%%{size_download}$%%{speed_download}$%%{http_version}$%%{ssl_verify_result}$%%{url_effective}\n`, n)
+}
+
+func parseLegacyWriteOut(line string) (writeOut, error) {
+	w := writeOut{
+		Exitcode: exitUnknown,
+	}
+	v := strings.SplitN(line, "$", 11)
+	if len(v) < 11 {
+		return w, fmt.Errorf("expected 11 fields, got %d", len(v))
+	}
+	var err error
+	w.ResponseCode, err = strconv.Atoi(v[0])
+	if err != nil {
+		return w, fmt.Errorf("can't convert response code '%s' to integer: %v", v[0], err)
+	}
+	w.TimeConnect, _ = strconv.ParseFloat(v[1], 64)
+	w.TimeAppconnect, _ = strconv.ParseFloat(v[2], 64)
+	w.TimeStarttransfer, _ = strconv.ParseFloat(v[3], 64)
+	w.RedirectURL = v[4]
+	w.RemoteIP = v[5]
+	w.SizeDownload, _ = strconv.ParseInt(v[6], 10, 64)
+	w.SpeedDownload, _ = strconv.ParseFloat(v[7], 64)
+	w.HTTPVersion = v[8]
+	w.SSLVerifyResult, _ = strconv.Atoi(v[9])
+	w.URLEffective = v[10]
+	return w, nil
+}
+
+// every option is the name alone or followed by its parameter, so the config file is written without guessing
+func formTransferKeys(basic [][]string, profile tlsprofile.Profile, plainHTTP bool, ipv int) [][]string {
+	keys := slices.Clone(basic)
+	keys = append(keys, []string{"-m", strconv.Itoa(options.MyOptions.ConnTimeout.Value)})
+	if ipv == 6 {
+		keys = append(keys, []string{"-6"})
+	} else {
+		keys = append(keys, []string{"-4"})
+	}
+	if strategy.Proxy != "noproxy" {
+		keys = append(keys, []string{"--proxy", strategy.Proxy})
+	}
+	if strategy.Protocol == "UDP" {
+		keys = append(keys, []string{"--http3-only"})
+	}
+	if options.MyOptions.SkipCertVerify.Value {
+		keys = append(keys, []string{"--insecure"})
+	}
+	if plainHTTP {
+		return keys
+	}
+	tlsKeys, _ := profile.CurlKeys(strategy.Protocol == "UDP")
+	keys = append(keys, tlsKeys...)
+	return keys
+}
+
+// curl options that take a parameter, as curl itself lists them in its help
+var takesParam map[string]bool
+
+var helpLine = regexp.MustCompile(`^\s*(?:(-[a-zA-Z0-9#:]), )?(--[a-zA-Z0-9.-]+)( <[^>]+>)?`)
+
+func readHelp() error {
+	takesParam = map[string]bool{}
+	// newer curl shows only a few categories unless asked for all of them
+	for _, args := range [][]string{{"--help", "all"}, {"--help"}} {
+		result, _ := exec.Command(options.MyOptions.Curl.ExecutableFullPath, args...).Output()
+		for _, line := range strings.Split(string(result), "\n") {
+			m := helpLine.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			if m[1] != "" {
+				takesParam[m[1]] = m[3] != ""
+			}
+			takesParam[m[2]] = m[3] != ""
+		}
+		if len(takesParam) > 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("can't read the list of options from curl help")
+}
+
+// pairKeys groups custom keys into options with their parameters
+func pairKeys(keys []string) ([][]string, error) {
+	if takesParam == nil {
+		err := readHelp()
+		if err != nil {
+			return nil, err
+		}
+	}
+	var opts [][]string
+	for i := 0; i < len(keys); i++ {
+		name := keys[i]
+		if len(name) < 2 || name[0] != '-' {
+			return nil, fmt.Errorf("'%s' isn't an option", name)
+		}
+		if name[1] != '-' && len(name) > 2 {
+			// short options may be glued together ('-sS') or with their parameter ('-m5')
+			if takesParam[name[:2]] {
+				opts = append(opts, []string{name[:2], name[2:]})
+				continue
+			}
+			for _, c := range name[1:] {
+				if needs, known := takesParam["-"+string(c)]; !known || needs {
+					return nil, fmt.Errorf("can't split '%s' into options", name)
+				}
+			}
+			opts = append(opts, []string{name})
+			continue
+		}
+		needs, known := takesParam[name]
+		if !known && strings.HasPrefix(name, "--no-") {
+			// boolean options are listed without their negative form
+			_, known = takesParam["--"+strings.TrimPrefix(name, "--no-")]
+			needs = false
+		}
+		if !known {
+			return nil, fmt.Errorf("option '%s' is unknown to curl", name)
+		}
+		if !needs {
+			opts = append(opts, []string{name})
+			continue
+		}
+		if i+1 == len(keys) {
+			return nil, fmt.Errorf("option '%s' needs a parameter", name)
+		}
+		opts = append(opts, []string{name, keys[i+1]})
+		i++
+	}
+	return opts, nil
+}
+
+func siteProfile(site checklist.Website) tlsprofile.Profile {
+	name := site.TLSProfile
+	if name == "" {
+		name = options.MyOptions.TLSProfile.Value
+	}
+	profile, err := tlsprofile.Lookup(name)
+	if err != nil {
+		log.Printf("Can't use TLS profile: %v\n", err)
+	}
+	return profile
+}
+
+// every run gets its own folder, so instances running side by side don't overwrite each other's files
+var runDir string
+
+// bodies are kept for block-page matching
+func bodyFile(n int) string {
+	return filepath.Join(runDir, fmt.Sprintf("body%d", n))
+}
+
+// all transfers go to curl through a config file, command line is too short for big checklists
+func configFile() string {
+	return filepath.Join(runDir, "requests.cfg")
+}
+
+// FormRequestsKeys writes every transfer into a config file and returns arguments pointing curl to it
+func FormRequestsKeys(_resolver string, addresses []checklist.Website) ([]string, error) {
+	var err error
+	if runDir == "" {
+		runDir, err = os.MkdirTemp("", "GoodCheckGoGo-")
+		if err != nil {
+			return nil, fmt.Errorf("can't create a folder for curl files: %v", err)
+		}
+		// removed by teardown on any exit
+		err = supervisor.RegisterTempDir(runDir)
+		if err != nil {
+			return nil, fmt.Errorf("can't register a folder for curl files: %v", err)
+		}
+	}
+	basic, err := pairKeys(basicKeys())
+	if err != nil {
+		return nil, fmt.Errorf("can't parse custom keys: %v", err)
+	}
+	var keys [][]string
+	var logged []string
+	// every target gets its own operation, so '--resolve' doesn't leak between IPs of the same host
+	n := 0
+	for _, addr := range addresses {
+		for _, target := range addr.Targets {
+			if n > 0 {
+				keys = append(keys, []string{"--next"})
+			}
+			profile := siteProfile(addr)
+			if _, skipped := profile.CurlKeys(strategy.Protocol == "UDP"); len(skipped) > 0 && !addr.PlainHTTP && !slices.Contains(logged, profile.Name) {
+				log.Printf("TLS profile '%s': %s can't be expressed with curl and will be ignored\n", profile.Name, strings.Join(skipped, ", "))
+				logged = append(logged, profile.Name)
+			}
+			keys = append(keys, formTransferKeys(basic, profile, addr.PlainHTTP, addr.IPV)...)
+			keys = append(keys, []string{"-w", writeOutKey(n)})
+			requestURL := checklist.RequestURL(addr)
+			port := 443
+			if addr.PlainHTTP {
+				port = 80
+			}
+			if options.MyOptions.ProbeMode.Value == "volume" {
+				if addr.VolumeURL != "" {
+					requestURL = addr.VolumeURL
+				}
+				// no need to download more than the volume
+				keys = append(keys, []string{"-r", fmt.Sprintf("0-%d", options.MyOptions.VolumeBytes.Value-1)})
+			}
+			keys = append(keys, []string{"--url", requestURL}, []string{"-o", bodyFile(n)})
+			if strategy.Proxy == "noproxy" && target.IP != "" {
+				keys = append(keys, []string{"--resolve", fmt.Sprintf("%s:%d:%s", utils.InsensitiveReplace(addr.Address, "https://", ""), port, target.IP)})
+			}
+			n++
+		}
+	}
+	if n > 1 || _resolver != "" {
+		keys = append(keys, []string{"-Z"}, []string{"--parallel-immediate"}, []string{"--parallel-max", "200"})
+	}
+	err = writeConfig(configFile(), keys)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Curl config with %d transfer(s) was written to '%s'\n", n, configFile())
+	return []string{"-K", configFile()}, nil
+}
+
+// writeConfig puts one option per line, its parameter is quoted
+func writeConfig(file string, keys [][]string) error {
+	var b strings.Builder
+	quote := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	for _, key := range keys {
+		b.WriteString(key[0])
+		for _, param := range key[1:] {
+			b.WriteString(` "` + quote.Replace(param) + `"`)
+		}
+		b.WriteString("\n")
+	}
+	err := os.WriteFile(file, []byte(b.String()), 0644)
+	if err != nil {
+		return fmt.Errorf("can't write curl config '%s': %v", file, err)
+	}
+	return nil
+}
+
+// SendRequestsAndParse kills curl as soon as ctx is cancelled
+func SendRequestsAndParse(ctx context.Context, keys []string, addresses *[]checklist.Website) error {
+	cmd := exec.CommandContext(ctx, options.MyOptions.Curl.ExecutableFullPath, keys...)
+
+	var targets []*checklist.Target
+	var sites []*checklist.Website
+	for i := range *addresses {
+		for j := range (*addresses)[i].Targets {
+			(*addresses)[i].Targets[j].LastResponseCode = 0
+			(*addresses)[i].Targets[j].LastOutcome = checklist.OutcomeOther
+			(*addresses)[i].Targets[j].LastError = ""
+			(*addresses)[i].Targets[j].BytesReceived = 0
+			(*addresses)[i].Targets[j].Throughput = 0
+			(*addresses)[i].Targets[j].RedirectChain = nil
+			(*addresses)[i].Targets[j].ALPN = ""
+			(*addresses)[i].Targets[j].HandshakeTime = 0
+			targets = append(targets, &(*addresses)[i].Targets[j])
+			sites = append(sites, &(*addresses)[i])
+		}
+	}
+
+	result, err := cmd.Output()
+	if ctx.Err() != nil {
+		return fmt.Errorf("curl was stopped: %v", ctx.Err())
+	}
+	if len(result) == 0 {
+		return fmt.Errorf("curl returned no results")
+	}
+	processExitcode := 0
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		processExitcode = exitErr.ExitCode()
+	}
+
+	resultString := string(result)
+	//log.Println("resultString:", resultString)
+	for _, line := range strings.Split(resultString, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "$" || line == "" {
+			continue
+		}
+		v := strings.SplitN(line, "$", 2)
+		index, err := strconv.Atoi(v[0])
+		if err != nil {
+			return fmt.Errorf("can't convert transfer number '%s' to integer: %v", v[0], err)
+		}
+		if index >= len(targets) || len(v) < 2 {
+			continue
+		}
+		var w writeOut
+		if jsonWriteOut {
+			// a missing field must not read as a success
+			w.Exitcode = exitUnknown
+			err = json.Unmarshal([]byte(v[1]), &w)
+		} else {
+			w, err = parseLegacyWriteOut(v[1])
+		}
+		if err != nil {
+			return fmt.Errorf("can't parse results of transfer %d: %v", index, err)
+		}
+		if w.Exitcode == exitUnknown && len(targets) == 1 {
+			w.Exitcode = processExitcode
+		}
+		applyWriteOut(sites[index], targets[index], index, w)
+	}
+	log.Println("Responses was received and parsed")
+	return nil
+}
+
+// applyWriteOut fills the target the same way native mode does
+func applyWriteOut(site *checklist.Website, target *checklist.Target, index int, w writeOut) {
+	target.LastResponseCode = w.ResponseCode
+	target.BytesReceived = w.SizeDownload
+	target.Throughput = w.SpeedDownload
+	target.ALPN = alpnNames[w.HTTPVersion]
+	if w.TimeConnect > 0 && w.TimeAppconnect > w.TimeConnect {
+		target.HandshakeTime = time.Duration((w.TimeAppconnect - w.TimeConnect) * float64(time.Second))
+	}
+	if w.RedirectURL != "" {
+		target.RedirectChain = []string{w.URLEffective, w.RedirectURL}
+	}
+	target.LastOutcome = classifyExitcode(w, site.PlainHTTP)
+	target.LastError = w.Errormsg
+	if w.ResponseCode == 0 {
+		os.Remove(bodyFile(index))
+		return
+	}
+	// without '--insecure' curl stops on a bad certificate by itself, so only insecure mode gets here
+	if w.SSLVerifyResult != 0 {
+		os.Remove(bodyFile(index))
+		target.LastResponseCode = 0
+		target.LastOutcome = checklist.OutcomeMITM
+		target.LastError = fmt.Sprintf("certificate verification failed with code %d", w.SSLVerifyResult)
+		return
+	}
+
+	body, _ := readBody(bodyFile(index))
+	matched, reason := blockpage.Match(blockpage.Response{
+		IP:          w.RemoteIP,
+		StatusCode:  w.ResponseCode,
+		RedirectURL: w.RedirectURL,
+		Body:        body,
+	})
+	if matched {
+		target.LastResponseCode = 0
+		target.LastOutcome = checklist.OutcomeBlockpage
+		target.LastError = reason
+		return
+	}
+	volume := int64(options.MyOptions.VolumeBytes.Value)
+	failed := w.Exitcode != 0 && w.Exitcode != exitUnknown
+	if options.MyOptions.ProbeMode.Value == "volume" && failed {
+		// status line is there, but the data froze midway
+		target.LastResponseCode = 0
+		// 18 is a partial file, the connection closed with the body unfinished
+		if target.LastOutcome == checklist.OutcomeHTTPTimeout || w.Exitcode == 18 {
+			target.LastOutcome = checklist.OutcomeStall
+		}
+		target.LastError = fmt.Sprintf("stalled at %d of %d bytes", w.SizeDownload, volume)
+		if w.Errormsg != "" {
+			target.LastError += ": " + w.Errormsg
+		}
+		return
+	}
+	target.LastOutcome = checklist.OutcomeSuccess
+	target.LastError = ""
+	if options.MyOptions.ProbeMode.Value == "volume" && w.SizeDownload < volume {
+		// the body ended cleanly before the volume, so the site works, it just has too little to tell a freeze past it
+		target.LastOutcome = checklist.OutcomeShortBody
+		target.LastError = fmt.Sprintf("body ended at %d of %d bytes", w.SizeDownload, volume)
+	}
+
+	if site.PlainHTTP && isInjectedRedirect(site, w.RedirectURL) {
+		log.Println("Suspicious redirection detected, treating as failure:", checklist.RequestURL(*site), "->", w.RedirectURL)
+		target.LastResponseCode = 0
+		target.LastOutcome = checklist.OutcomeRedirect
+		target.LastError = "redirect to " + w.RedirectURL
+	}
+}
+
+func readBody(file string) ([]byte, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file)
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, blockpage.MaxBody))
+}
+
+// ISPs answer plain HTTP requests with a redirect to their own stub; the usual upgrade to HTTPS stays on the same site
+func isInjectedRedirect(site *checklist.Website, redirectURL string) bool {
+	if redirectURL == "" {
+		return false
+	}
+	u, err := url.Parse(redirectURL)
+	if err != nil {
+		return true
+	}
+	host := utils.InsensitiveReplace(site.Address, "https://", "")
+	return !checklist.SameSite(host, u.Hostname()) && !checklist.IsAllowedRedirect(u.Hostname(), site.RedirectAllow)
+}
+
+func classifyExitcode(w writeOut, plainHTTP bool) string {
+	msg := strings.ToLower(w.Errormsg)
+	reset := strings.Contains(msg, "reset") || strings.Contains(msg, "refused") || strings.Contains(msg, "10054") || strings.Contains(msg, "10061")
+	switch w.Exitcode {
+	case exitUnknown:
+		// nothing tells a timeout from a reset here, only a response proves the transfer went through
+		if w.ResponseCode != 0 {
+			return checklist.OutcomeSuccess
+		}
+		return checklist.OutcomeOther
+	case 0:
+		return checklist.OutcomeSuccess
+	case 5, 6:
+		// couldn't resolve proxy or host
+		return checklist.OutcomeDNS
+	case 7:
+		if strings.Contains(msg, "timed out") {
+			return checklist.OutcomeTCPTimeout
+		}
+		return checklist.OutcomeTCPRST
+	case 28:
+		switch {
+		case strategy.Protocol == "UDP" && w.TimeAppconnect == 0:
+			return checklist.OutcomeQUICTimeout
+		case w.TimeConnect == 0:
+			return checklist.OutcomeTCPTimeout
+		case w.TimeAppconnect == 0 && !plainHTTP:
+			return checklist.OutcomeTLSTimeout
+		case w.TimeStarttransfer > 0:
+			// first byte came, the rest didn't
+			return checklist.OutcomeStall
+		}
+		return checklist.OutcomeHTTPTimeout
+	case 35:
+		// schannel reports reset during handshake as a failure to receive it
+		if strings.Contains(msg, "alert") {
+			return checklist.OutcomeTLSAlert
+		}
+		if reset || strings.Contains(msg, "failed to receive handshake") {
+			return checklist.OutcomeTCPRST
+		}
+	case 51, 60:
+		return checklist.OutcomeCertMismatch
+	case 55, 56:
+		if reset {
+			return checklist.OutcomeTCPRST
+		}
+	}
+	return checklist.OutcomeOther
+}
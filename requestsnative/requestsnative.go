@@ -1,263 +1,786 @@
-package requestsnative
-
-import (
-	"context"
-	"crypto/tls"
-	"crypto/x509"
-	"io"
-	"math/rand/v2"
-	"strings"
-	"sync"
-
-	// "crypto/x509"
-	"fmt"
-	"goodcheckgogo/checklist"
-	"goodcheckgogo/options"
-	"goodcheckgogo/strategy"
-	"goodcheckgogo/utils"
-	"log"
-	"net"
-	"net/http"
-	"time"
-
-	"github.com/quic-go/quic-go"
-	"github.com/quic-go/quic-go/http3"
-)
-
-var (
-	_dialer = &net.Dialer{
-		KeepAlive:     -1,
-		FallbackDelay: -1,
-	}
-
-	_tlsConfig = &tls.Config{
-		//InsecureSkipVerify: options.MyOptions.SkipCertVerify.Value,
-	}
-
-	_transport = &http.Transport{
-		DisableKeepAlives:   true,
-		DisableCompression:  true,
-		IdleConnTimeout:     1 * time.Second,
-		TLSClientConfig:     _tlsConfig,
-		MaxIdleConns:        -1,
-		MaxIdleConnsPerHost: -1,
-	}
-
-	_quicConfig = &quic.Config{
-		//MaxIncomingStreams:    int64(threads),
-		//MaxIncomingUniStreams: int64(threads),
-		KeepAlivePeriod: 0,
-		MaxIdleTimeout:  2 * time.Second,
-	}
-
-	_transportH3 = &http3.Transport{
-		TLSClientConfig: _tlsConfig,
-		QUICConfig:      _quicConfig,
-	}
-
-	_client = &http.Client{
-		//Timeout: time.Duration(options.MyOptions.ConnTimeout.Value) * time.Second,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			domain1parts := strings.Split(via[0].URL.Hostname(), ".")
-			domain2parts := strings.Split(req.URL.Hostname(), ".")
-			if domain1parts[len(domain1parts)-2] != domain2parts[len(domain2parts)-2] {
-				log.Println("Suspicious redirection detected, treating as failure:", via[0].URL, "->", req.URL)
-				return fmt.Errorf("bad redirection")
-			} else {
-				log.Println("Safe redirection detected:", via[0].URL, "->", req.URL)
-				return http.ErrUseLastResponse
-			}
-		},
-	}
-)
-
-var poolAlreadyReaded = false
-
-func SetTransport(threads int, timeout int) {
-	_quicConfig.MaxIncomingStreams = int64(threads)
-	_quicConfig.MaxIncomingUniStreams = int64(threads)
-	_client.Timeout = time.Duration(timeout) * time.Second
-	_tlsConfig.InsecureSkipVerify = options.MyOptions.SkipCertVerify.Value
-	if !options.MyOptions.SkipCertVerify.Value && !poolAlreadyReaded {
-		_tlsConfig.InsecureSkipVerify = false
-		pool, err := x509.SystemCertPool()
-		if err != nil {
-			log.Printf("Can't read system certificates pool: %v\n", err)
-		} else {
-			log.Printf("Certificates pool successfully readed")
-			_tlsConfig.RootCAs = pool
-		}
-		poolAlreadyReaded = true
-	} else {
-		_tlsConfig.InsecureSkipVerify = true
-	}
-}
-
-func CloseIdle() {
-	_client.CloseIdleConnections()
-}
-
-func CheckConnectivityNative() error {
-
-	_request, err := http.NewRequest("GET", options.MyOptions.NetConnTestURL.Value, nil)
-	if err != nil {
-		return fmt.Errorf("can't form request: %v", err)
-	}
-
-	// switch strategy.Protocol {
-	// case "TCP":
-	_transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
-		return _dialer.DialContext(ctx, fmt.Sprintf("tcp%d", strategy.IPV), addr)
-	}
-	_client.Transport = _transport
-	// case "UDP":
-	// 	_transportH3.Dial = func(ctx context.Context, addr string, tlsConf *tls.Config, quicConf *quic.Config) (quic.EarlyConnection, error) {
-	// 		udpAddr, err := net.Dial(strategy.ProtoFull, addr)
-	// 		if err != nil {
-	// 			log.Printf("error setting UDP transport dialer: %v", err)
-	// 			return nil, err
-	// 		}
-	// 		log.Printf("Dialing %s...", udpAddr.RemoteAddr().String())
-	// 		return quic.DialAddrEarly(ctx, udpAddr.RemoteAddr().String(), tlsConf, quicConf)
-	// 	}
-	// 	_client.Transport = _transportH3
-	// }
-
-	if !options.MyOptions.SkipCertVerify.Value {
-		log.Printf("Making normal request to '%s' (Native)\n", options.MyOptions.NetConnTestURL.Value)
-	} else {
-		log.Printf("Making insecure request to '%s' (Native)\n", options.MyOptions.NetConnTestURL.Value)
-	}
-
-	_response, err := _client.Do(_request)
-	if err != nil || _response == nil {
-		return fmt.Errorf("can't get proper response: %v", err)
-	}
-	defer _response.Body.Close()
-
-	if _response.StatusCode == 0 {
-		return fmt.Errorf("can't properly verify connection: response code 0")
-	} else {
-		log.Printf("Connection seems ok; response code: %d %s\n", _response.StatusCode, http.StatusText(_response.StatusCode))
-		return nil
-	}
-}
-
-func ExtractClusterNative(mappingURL string) string {
-	log.Printf("Attempting to extract cluster codename from '%s'...\n", mappingURL)
-
-	resp, err := http.Get(mappingURL)
-	if err != nil || resp.Body == nil {
-		return ""
-	}
-	defer resp.Body.Close()
-
-	b, err := io.ReadAll(resp.Body)
-	if err != nil || len(b) == 0 {
-		return ""
-	}
-	s := string(b)
-	ss := strings.Split(s, " ")[2]
-
-	return ss
-}
-
-// func SetProxy() {
-// 	if strategy.Proxy != "noproxy" {
-// 		log.Println("\nSetting up proxy for native mode...")
-// 		s := strings.Split(strategy.Proxy, ":")[0]
-// 		h := strings.Split(strategy.Proxy, "/")[1]
-// 		var proxyUrl = &url.URL{
-// 			Scheme: s,
-// 			Host:   h,
-// 		}
-// 		_transport.Proxy = http.ProxyURL(proxyUrl)
-// 		log.Println("Proxy scheme: %s\nProxy address: %s\n", s, h)
-// 	}
-// }
-
-func extractDomain(url string) string {
-	withReplaces := utils.InsensitiveReplace(url, "http://", "")
-	withReplaces = utils.InsensitiveReplace(withReplaces, "https://", "")
-	withReplaces = utils.InsensitiveReplace(withReplaces, "www.", "")
-
-	return withReplaces
-}
-
-func SendRequest(wg *sync.WaitGroup, site *checklist.Website, sites []checklist.Website) {
-	defer wg.Done()
-
-	site.LastResponseCode = 0
-
-	_request, err := http.NewRequest("GET", site.Address, nil)
-	if err != nil {
-		log.Printf("Problem with a request: %v\n", err)
-		return
-	}
-
-	switch strategy.Protocol {
-	case "UDP":
-		_transportH3.Dial = func(ctx context.Context, addr string, tlsConf *tls.Config, quicConf *quic.Config) (quic.EarlyConnection, error) {
-			if strategy.Proxy != "noproxy" {
-				return quic.DialAddrEarly(ctx, addr, tlsConf, quicConf)
-			}
-			a := ""
-			for i := 0; i < len(sites); i++ {
-				if extractDomain(addr) == extractDomain(sites[i].Address)+":443" {
-					if strategy.IPV == 6 {
-						a = "[" + sites[i].IP + "]:443"
-					} else {
-						a = sites[i].IP + ":443"
-					}
-					break
-				}
-			}
-			if a == "" {
-				log.Panicf("Panic: can't assign IP to '%s'\n", addr)
-			}
-			return quic.DialAddrEarly(ctx, a, tlsConf, quicConf)
-		}
-		//defer _transportH3.Close()
-		_client.Transport = _transportH3
-	case "TCP":
-		_transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
-			if strategy.Proxy != "noproxy" {
-				return _dialer.DialContext(ctx, strategy.ProtoFull, addr)
-			}
-			a := ""
-			for i := 0; i < len(sites); i++ {
-				if extractDomain(addr) == extractDomain(sites[i].Address)+":443" {
-					if strategy.IPV == 6 {
-						a = "[" + sites[i].IP + "]:443"
-					} else {
-						a = sites[i].IP + ":443"
-					}
-					break
-				}
-			}
-			if a == "" {
-				log.Panicf("Panic: can't assign IP to '%s'\n", addr)
-			}
-			return _dialer.DialContext(ctx, strategy.ProtoFull, a)
-		}
-		_client.Transport = _transport
-	}
-
-	r := rand.IntN(options.MyOptions.InternalTimeoutMs.Value)
-	time.Sleep(time.Duration(r) * time.Millisecond)
-	_response, err := _client.Do(_request)
-	if err != nil && utils.UnwrapErrCompletely(err).Error() == "invalid header field name: \"connection\"" {
-		site.LastResponseCode = 418
-		site.HasSuccesses = true
-		return
-	}
-	if err != nil {
-		site.LastResponseCode = 0
-		//log.Println("err:", err.Error())
-		return
-	}
-	defer _response.Body.Close()
-
-	site.LastResponseCode = _response.StatusCode
-	site.HasSuccesses = true
-}
+package requestsnative
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	// "crypto/x509"
+	"fmt"
+	"goodcheckgogo/blockpage"
+	"goodcheckgogo/certcheck"
+	"goodcheckgogo/checklist"
+	"goodcheckgogo/options"
+	"goodcheckgogo/strategy"
+	"goodcheckgogo/supervisor"
+	"goodcheckgogo/tlsprofile"
+	"goodcheckgogo/utils"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	xproxy "golang.org/x/net/proxy"
+	"golang.org/x/sys/windows"
+)
+
+var (
+	_dialer = &net.Dialer{
+		KeepAlive:     -1,
+		FallbackDelay: -1,
+	}
+
+	_tlsConfig = &tls.Config{
+		//InsecureSkipVerify: options.MyOptions.SkipCertVerify.Value,
+	}
+
+	_transport = &http.Transport{
+		DisableKeepAlives:   true,
+		DisableCompression:  true,
+		IdleConnTimeout:     1 * time.Second,
+		TLSClientConfig:     _tlsConfig,
+		MaxIdleConns:        -1,
+		MaxIdleConnsPerHost: -1,
+	}
+
+	// one transport and TLS config per TLS profile in use
+	_routedTransports map[string]*http.Transport
+	_profileTLS       map[string]*tls.Config
+
+	_quicConfig = &quic.Config{
+		//MaxIncomingStreams:    int64(threads),
+		//MaxIncomingUniStreams: int64(threads),
+		KeepAlivePeriod: 0,
+		MaxIdleTimeout:  2 * time.Second,
+	}
+
+	_client = &http.Client{
+		//Timeout: time.Duration(options.MyOptions.ConnTimeout.Value) * time.Second,
+	}
+)
+
+var (
+	errBlockpage = errors.New("blockpage")
+	errRedirect  = errors.New("bad redirection")
+)
+
+// checkRedirect applies redirect policy and writes every hop to chain
+func checkRedirect(allowlist []string, plainHTTP bool, chain *[]string) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if len(*chain) == 0 {
+			*chain = append(*chain, via[0].URL.String())
+		}
+		*chain = append(*chain, req.URL.String())
+
+		if blockpage.MatchHost(req.URL.Hostname()) {
+			return fmt.Errorf("%w: redirect to %s", errBlockpage, req.URL.Hostname())
+		}
+		policy := options.MyOptions.RedirectPolicy.Value
+		if policy == "fail" {
+			return fmt.Errorf("%w: redirects aren't allowed: %s -> %s", errRedirect, via[len(via)-1].URL, req.URL)
+		}
+		if !checklist.SameSite(via[0].URL.Hostname(), req.URL.Hostname()) && !checklist.IsAllowedRedirect(req.URL.Hostname(), allowlist) {
+			log.Println("Suspicious redirection detected, treating as failure:", via[len(via)-1].URL, "->", req.URL)
+			return fmt.Errorf("%w: %s -> %s", errRedirect, via[len(via)-1].URL, req.URL)
+		}
+		// following the usual upgrade to HTTPS would test something else than port 80
+		if policy == "stop" || plainHTTP {
+			return http.ErrUseLastResponse
+		}
+		if len(via) >= 10 {
+			return fmt.Errorf("%w: stopped after 10 redirects", errRedirect)
+		}
+		return nil
+	}
+}
+
+var poolAlreadyReaded = false
+
+func SetTransport(threads int, timeout int) {
+	_quicConfig.MaxIncomingStreams = int64(threads)
+	_quicConfig.MaxIncomingUniStreams = int64(threads)
+	_client.Timeout = time.Duration(timeout) * time.Second
+	_tlsConfig.InsecureSkipVerify = options.MyOptions.SkipCertVerify.Value
+	if !options.MyOptions.SkipCertVerify.Value && !poolAlreadyReaded {
+		_tlsConfig.InsecureSkipVerify = false
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			log.Printf("Can't read system certificates pool: %v\n", err)
+		} else {
+			log.Printf("Certificates pool successfully readed")
+			_tlsConfig.RootCAs = pool
+		}
+		poolAlreadyReaded = true
+	} else {
+		_tlsConfig.InsecureSkipVerify = true
+	}
+}
+
+func CloseIdle() {
+	for _, transport := range _routedTransports {
+		transport.CloseIdleConnections()
+	}
+}
+
+func CheckConnectivityNative(ipv int) error {
+
+	_request, err := http.NewRequest("GET", options.MyOptions.NetConnTestURL.Value, nil)
+	if err != nil {
+		return fmt.Errorf("can't form request: %v", err)
+	}
+
+	// switch strategy.Protocol {
+	// case "TCP":
+	// always direct: proxy is provided by the fooling program, which isn't running yet
+	transport := _transport.Clone()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return _dialer.DialContext(ctx, fmt.Sprintf("tcp%d", ipv), addr)
+	}
+	defer transport.CloseIdleConnections()
+	var chain []string
+	client := &http.Client{
+		Timeout:       _client.Timeout,
+		CheckRedirect: checkRedirect(nil, false, &chain),
+		Transport:     transport,
+	}
+	// case "UDP":
+	// 	_transportH3.Dial = func(ctx context.Context, addr string, tlsConf *tls.Config, quicConf *quic.Config) (quic.EarlyConnection, error) {
+	// 		udpAddr, err := net.Dial(strategy.ProtoFull, addr)
+	// 		if err != nil {
+	// 			log.Printf("error setting UDP transport dialer: %v", err)
+	// 			return nil, err
+	// 		}
+	// 		log.Printf("Dialing %s...", udpAddr.RemoteAddr().String())
+	// 		return quic.DialAddrEarly(ctx, udpAddr.RemoteAddr().String(), tlsConf, quicConf)
+	// 	}
+	// 	_client.Transport = _transportH3
+	// }
+
+	if !options.MyOptions.SkipCertVerify.Value {
+		log.Printf("Making normal request to '%s' (Native)\n", options.MyOptions.NetConnTestURL.Value)
+	} else {
+		log.Printf("Making insecure request to '%s' (Native)\n", options.MyOptions.NetConnTestURL.Value)
+	}
+
+	_response, err := client.Do(_request)
+	if err != nil || _response == nil {
+		return fmt.Errorf("can't get proper response: %v", err)
+	}
+	defer _response.Body.Close()
+
+	if _response.StatusCode == 0 {
+		return fmt.Errorf("can't properly verify connection: response code 0")
+	} else {
+		log.Printf("Connection seems ok; response code: %d %s\n", _response.StatusCode, http.StatusText(_response.StatusCode))
+		return nil
+	}
+}
+
+func ExtractClusterNative(mappingURL string) string {
+	log.Printf("Attempting to extract cluster codename from '%s'...\n", mappingURL)
+
+	resp, err := http.Get(mappingURL)
+	if err != nil || resp.Body == nil {
+		return ""
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil || len(b) == 0 {
+		return ""
+	}
+	s := string(b)
+	ss := strings.Split(s, " ")[2]
+
+	return ss
+}
+
+var _proxy *url.URL
+
+func ParseProxy(proxyAddr string) (*url.URL, error) {
+	u, err := url.Parse(proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse proxy address '%s': %v", proxyAddr, err)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "socks5", "socks5h", "http":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme '%s': native mode supports socks5, socks5h and http", u.Scheme)
+	}
+	if u.Hostname() == "" || u.Port() == "" {
+		return nil, fmt.Errorf("proxy address '%s' must have a host and a port", proxyAddr)
+	}
+	return u, nil
+}
+
+func SetProxy(proxyAddr string) error {
+	if proxyAddr == "noproxy" {
+		_proxy = nil
+		return nil
+	}
+	if strategy.Protocol == "UDP" {
+		return fmt.Errorf("native mode can't send QUIC through a proxy")
+	}
+	u, err := ParseProxy(proxyAddr)
+	if err != nil {
+		return err
+	}
+	_proxy = u
+	log.Printf("Proxy scheme: %s\nProxy address: %s\n", u.Scheme, u.Host)
+	return nil
+}
+
+// newTransport sends requests either through the proxy or through the given dialer
+func newTransport(dial func(ctx context.Context, network, addr string) (net.Conn, error)) *http.Transport {
+	transport := _transport.Clone()
+	if _proxy == nil {
+		transport.DialContext = dial
+		return transport
+	}
+	switch strings.ToLower(_proxy.Scheme) {
+	case "http":
+		// proxy authorization is taken from the url by transport itself
+		transport.Proxy = http.ProxyURL(_proxy)
+		transport.DialContext = _dialer.DialContext
+	case "socks5", "socks5h":
+		transport.DialContext = dialSOCKS5
+	}
+	return transport
+}
+
+func dialSOCKS5(ctx context.Context, network, addr string) (net.Conn, error) {
+	var auth *xproxy.Auth
+	if _proxy.User != nil {
+		password, _ := _proxy.User.Password()
+		auth = &xproxy.Auth{
+			User:     _proxy.User.Username(),
+			Password: password,
+		}
+	}
+	d, err := xproxy.SOCKS5("tcp", _proxy.Host, auth, _dialer)
+	if err != nil {
+		return nil, fmt.Errorf("can't set up socks5 dialer: %v", err)
+	}
+
+	// socks5 resolves names on this side, socks5h leaves it to the proxy
+	if strings.ToLower(_proxy.Scheme) == "socks5" {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse address '%s': %v", addr, err)
+		}
+		// the site's own IP version, not the global one, when both are tested
+		ipv, ok := ctx.Value(ipvKey{}).(int)
+		if !ok {
+			ipv = strategy.IPV
+		}
+		ips, err := net.DefaultResolver.LookupNetIP(ctx, fmt.Sprintf("ip%d", ipv), host)
+		if err != nil || len(ips) == 0 {
+			return nil, fmt.Errorf("can't resolve '%s' for socks5: %v", host, err)
+		}
+		addr = net.JoinHostPort(ips[0].String(), port)
+	}
+	return d.(xproxy.ContextDialer).DialContext(ctx, "tcp", addr)
+}
+
+type targetKey struct{}
+
+// IP version of the site, dual-stack runs have both of them in flight
+type ipvKey struct{}
+
+func dialNetwork(ctx context.Context) string {
+	ipv, ok := ctx.Value(ipvKey{}).(int)
+	if !ok {
+		return strategy.ProtoFull
+	}
+	n, err := strategy.Network(ipv)
+	if err != nil {
+		return strategy.ProtoFull
+	}
+	return n
+}
+
+// host -> addresses, built once before requests and only read afterwards
+var _routes map[string][]string
+
+// SetRoutes must be called before any request is sent; nothing modifies routes or transport after that
+func SetRoutes(sites []checklist.Website) error {
+	if options.MyOptions.ProbeMode.Value == "handshake" && _proxy != nil && strings.ToLower(_proxy.Scheme) == "http" {
+		return fmt.Errorf("handshake probe can't go through http proxy, use socks5 or socks5h")
+	}
+	routes := make(map[string][]string, len(sites))
+	profiles := []string{options.MyOptions.TLSProfile.Value}
+	for _, site := range sites {
+		u, err := url.Parse(site.Address)
+		if err != nil {
+			log.Printf("Can't parse '%s': %v\n", site.Address, err)
+			continue
+		}
+		for _, target := range site.Targets {
+			routes[u.Hostname()] = append(routes[u.Hostname()], target.IP)
+		}
+		if site.TLSProfile != "" && !slices.Contains(profiles, site.TLSProfile) {
+			profiles = append(profiles, site.TLSProfile)
+		}
+	}
+	_routes = routes
+
+	transports := map[string]*http.Transport{}
+	configs := map[string]*tls.Config{}
+	for _, name := range profiles {
+		profile, err := tlsprofile.Lookup(name)
+		if err != nil {
+			return err
+		}
+		if strategy.Protocol == "UDP" && profile.MaxVersion != 0 && profile.MaxVersion < tls.VersionTLS13 {
+			return fmt.Errorf("TLS profile '%s' can't be used with QUIC, which requires TLS 1.3", name)
+		}
+		transport := newTransport(func(ctx context.Context, network, addr string) (net.Conn, error) {
+			a, err := routeAddr(ctx, addr)
+			if err != nil {
+				return nil, err
+			}
+			return _dialer.DialContext(ctx, dialNetwork(ctx), a)
+		})
+		transport.TLSClientConfig = profile.Config(_tlsConfig, true)
+		// custom TLS config turns HTTP/2 off unless asked explicitly
+		transport.ForceAttemptHTTP2 = profile.HasALPN("h2")
+		if profile.SNI != "" {
+			// transport verifies against ServerName, which is the override here, not the URL host
+			if _proxy != nil && strings.ToLower(_proxy.Scheme) == "http" {
+				return fmt.Errorf("TLS profile '%s' overrides SNI, which can't go through http proxy, use socks5 or socks5h", name)
+			}
+			transport.DialTLSContext = dialTLS(transport.DialContext, transport.TLSClientConfig)
+		}
+		transports[name] = transport
+		configs[name] = profile.Config(_tlsConfig, false)
+		log.Printf("TLS profile: %s\n", profile.Describe())
+	}
+	_routedTransports = transports
+	_profileTLS = configs
+	return nil
+}
+
+// dialTLS does the handshake the transport would, but checks the certificate against the host being dialed
+func dialTLS(dial func(ctx context.Context, network, addr string) (net.Conn, error), conf *tls.Config) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse address '%s': %v", addr, err)
+		}
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		tlsConn := tls.Client(conn, tlsprofile.ForHost(conf, host))
+		err = tlsConn.HandshakeContext(ctx)
+		// transport reports the handshake only when it does it by itself
+		if trace := httptrace.ContextClientTrace(ctx); trace != nil && trace.TLSHandshakeDone != nil {
+			trace.TLSHandshakeDone(tlsConn.ConnectionState(), err)
+		}
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
+	}
+}
+
+func siteProfile(site *checklist.Website) string {
+	if site.TLSProfile != "" {
+		return site.TLSProfile
+	}
+	return options.MyOptions.TLSProfile.Value
+}
+
+func routeAddr(ctx context.Context, addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("can't parse address '%s': %v", addr, err)
+	}
+	ips, ok := _routes[host]
+	// hosts outside of the checklist (volume URLs, redirect targets) and proxy mode go by name
+	if !ok || slices.Contains(ips, "") {
+		return addr, nil
+	}
+	// target IP belongs to the host the request started with; a redirect to another checklist host uses that host's own route
+	ip, _ := ctx.Value(targetKey{}).(string)
+	if !slices.Contains(ips, ip) {
+		ip = ips[0]
+	}
+	return net.JoinHostPort(ip, port), nil
+}
+
+// how far a request got before failing
+const (
+	stageConnect int32 = iota
+	stageTLS
+	stageHTTP
+)
+
+// SendRequest gives up as soon as ctx is cancelled
+func SendRequest(ctx context.Context, wg *sync.WaitGroup, site *checklist.Website, target *checklist.Target) {
+	defer wg.Done()
+	defer supervisor.Guard()
+
+	target.LastResponseCode = 0
+	target.LastOutcome = checklist.OutcomeOther
+	target.LastError = ""
+	target.BytesReceived = 0
+	target.Throughput = 0
+	target.RedirectChain = nil
+	target.TLSVersion = ""
+	target.ALPN = ""
+	target.CertSubject = ""
+	target.CertIssuer = ""
+	target.CertSPKI = ""
+	target.CertNotAfter = time.Time{}
+	target.HandshakeTime = 0
+
+	if options.MyOptions.ProbeMode.Value == "handshake" {
+		probeHandshake(ctx, site, target)
+		return
+	}
+
+	var stage atomic.Int32
+	trace := &httptrace.ClientTrace{
+		ConnectDone: func(network, addr string, err error) {
+			if err == nil && site.PlainHTTP {
+				stage.Store(stageHTTP)
+			} else if err == nil {
+				stage.Store(stageTLS)
+			}
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				stage.Store(stageHTTP)
+			}
+		},
+	}
+	ctx = context.WithValue(ctx, targetKey{}, target.IP)
+	ctx = context.WithValue(ctx, ipvKey{}, site.IPV)
+	ctx = httptrace.WithClientTrace(ctx, trace)
+	requestURL := checklist.RequestURL(*site)
+	volume := options.MyOptions.ProbeMode.Value == "volume"
+	if volume && site.VolumeURL != "" {
+		requestURL = site.VolumeURL
+	}
+	_request, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
+	if err != nil {
+		log.Printf("Problem with a request: %v\n", err)
+		target.LastError = err.Error()
+		return
+	}
+	if volume {
+		// same range curl asks for, so both modes download the same amount
+		_request.Header.Set("Range", fmt.Sprintf("bytes=0-%d", options.MyOptions.VolumeBytes.Value-1))
+	}
+
+	var chain []string
+	defer func() {
+		target.RedirectChain = chain
+	}()
+	client := &http.Client{
+		Timeout:       _client.Timeout,
+		CheckRedirect: checkRedirect(site.RedirectAllow, site.PlainHTTP, &chain),
+	}
+
+	tlsConf, ok := _profileTLS[siteProfile(site)]
+	if !ok {
+		log.Printf("Problem with a request: routes are not set\n")
+		target.LastError = "routes are not set"
+		return
+	}
+
+	switch strategy.Protocol {
+	case "UDP":
+		// http3 keeps a single connection per host, so every target needs its own transport
+		transportH3 := &http3.Transport{
+			TLSClientConfig: tlsConf,
+			QUICConfig:      _quicConfig,
+			Dial: func(ctx context.Context, addr string, tlsConf *tls.Config, quicConf *quic.Config) (quic.EarlyConnection, error) {
+				a, err := routeAddr(ctx, addr)
+				if err != nil {
+					return nil, err
+				}
+				host, _, _ := net.SplitHostPort(addr)
+				// handshake is a part of dialing in QUIC
+				conn, err := quic.DialAddrEarly(ctx, a, tlsprofile.ForHost(tlsConf, host), quicConf)
+				if err == nil {
+					stage.Store(stageHTTP)
+				}
+				return conn, err
+			},
+		}
+		defer transportH3.Close()
+		client.Transport = transportH3
+	case "TCP", "HTTP":
+		transport := _routedTransports[siteProfile(site)]
+		if transport.ForceAttemptHTTP2 {
+			// http2 pools connections by host, not by IP, so targets of the same host can't share a transport
+			transport = transport.Clone()
+			defer transport.CloseIdleConnections()
+		}
+		client.Transport = transport
+	}
+
+	if !jitter(ctx) {
+		target.LastError = ctx.Err().Error()
+		return
+	}
+	start := time.Now()
+	_response, err := client.Do(_request)
+	if err != nil && utils.UnwrapErrCompletely(err).Error() == "invalid header field name: \"connection\"" {
+		target.LastResponseCode = 418
+		target.LastOutcome = checklist.OutcomeSuccess
+		return
+	}
+	if err != nil {
+		target.LastResponseCode = 0
+		target.LastOutcome = classifyError(err, stage.Load(), strategy.Protocol == "UDP")
+		target.LastError = utils.UnwrapErrCompletely(err).Error()
+		return
+	}
+	defer _response.Body.Close()
+
+	// in insecure mode this is the only thing standing between a substituted certificate and a success
+	if _response.TLS != nil && inspectCert(target, _response.Request.URL.Hostname(), _response.TLS) {
+		return
+	}
+
+	limit := int64(blockpage.MaxBody)
+	if volume {
+		limit = int64(options.MyOptions.VolumeBytes.Value)
+	}
+	body, n, err := readVolume(_response.Body, limit)
+	target.BytesReceived = n
+	if elapsed := time.Since(start).Seconds(); elapsed > 0 {
+		target.Throughput = float64(n) / elapsed
+	}
+	matched, reason := blockpage.Match(blockpage.Response{
+		IP:          target.IP,
+		StatusCode:  _response.StatusCode,
+		RedirectURL: _response.Header.Get("Location"),
+		Body:        body,
+	})
+	if matched {
+		target.LastOutcome = checklist.OutcomeBlockpage
+		target.LastError = reason
+		return
+	}
+	if volume && err != nil {
+		// status line is there, but the data froze midway
+		target.LastOutcome = checklist.OutcomeStall
+		if o := classifyError(err, stageHTTP, false); o != checklist.OutcomeHTTPTimeout {
+			target.LastOutcome = o
+		}
+		target.LastError = fmt.Sprintf("stalled at %d of %d bytes: %v", n, limit, utils.UnwrapErrCompletely(err))
+		return
+	}
+
+	target.LastResponseCode = _response.StatusCode
+	target.LastOutcome = checklist.OutcomeSuccess
+	if volume && n < limit {
+		// the body ended cleanly before the volume, so the site works, it just has too little to tell a freeze past it
+		target.LastOutcome = checklist.OutcomeShortBody
+		target.LastError = fmt.Sprintf("body ended at %d of %d bytes", n, limit)
+	}
+}
+
+// probeHandshake stops right after TLS or QUIC handshake, so HTTP behavior of the server doesn't matter
+func probeHandshake(ctx context.Context, site *checklist.Website, target *checklist.Target) {
+	tlsConf, ok := _profileTLS[siteProfile(site)]
+	if !ok {
+		log.Printf("Problem with a handshake: routes are not set\n")
+		target.LastError = "routes are not set"
+		return
+	}
+	u, err := url.Parse(site.Address)
+	if err != nil {
+		target.LastError = err.Error()
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithValue(context.WithValue(ctx, targetKey{}, target.IP), ipvKey{}, site.IPV), _client.Timeout)
+	defer cancel()
+	addr, err := routeAddr(ctx, net.JoinHostPort(u.Hostname(), "443"))
+	if err != nil {
+		target.LastError = err.Error()
+		return
+	}
+	conf := tlsConf.Clone()
+	if conf.ServerName == "" {
+		conf.ServerName = u.Hostname()
+	}
+	conf = tlsprofile.ForHost(conf, u.Hostname())
+
+	if !jitter(ctx) {
+		target.LastError = ctx.Err().Error()
+		return
+	}
+	start := time.Now()
+	var state tls.ConnectionState
+	stage := stageConnect
+	switch strategy.Protocol {
+	case "UDP":
+		conf.NextProtos = []string{http3.NextProtoH3}
+		conn, err := quic.DialAddr(ctx, addr, conf, _quicConfig)
+		if err != nil {
+			target.LastOutcome = classifyError(err, stage, true)
+			target.LastError = utils.UnwrapErrCompletely(err).Error()
+			return
+		}
+		defer conn.CloseWithError(0, "")
+		state = conn.ConnectionState().TLS
+	case "TCP", "HTTP":
+		// ALPN and padding come with the transport config of the profile
+		conf.NextProtos = _routedTransports[siteProfile(site)].TLSClientConfig.NextProtos
+		var conn net.Conn
+		if _proxy != nil {
+			conn, err = dialSOCKS5(ctx, "tcp", addr)
+		} else {
+			conn, err = _dialer.DialContext(ctx, dialNetwork(ctx), addr)
+		}
+		if err != nil {
+			target.LastOutcome = classifyError(err, stage, false)
+			target.LastError = utils.UnwrapErrCompletely(err).Error()
+			return
+		}
+		defer conn.Close()
+		stage = stageTLS
+		tlsConn := tls.Client(conn, conf)
+		err = tlsConn.HandshakeContext(ctx)
+		if err != nil {
+			target.LastOutcome = classifyError(err, stage, false)
+			target.LastError = utils.UnwrapErrCompletely(err).Error()
+			return
+		}
+		state = tlsConn.ConnectionState()
+	}
+
+	target.HandshakeTime = time.Since(start)
+	target.TLSVersion = tls.VersionName(state.Version)
+	target.ALPN = state.NegotiatedProtocol
+	if inspectCert(target, u.Hostname(), &state) {
+		return
+	}
+	// there is no status code, any positive one marks success
+	target.LastResponseCode = 1
+	target.LastOutcome = checklist.OutcomeSuccess
+}
+
+// jitter spreads requests a little; false means ctx ended while waiting
+func jitter(ctx context.Context) bool {
+	r := rand.IntN(options.MyOptions.InternalTimeoutMs.Value)
+	select {
+	case <-time.After(time.Duration(r) * time.Millisecond):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// inspectCert records the chain and reports true when it looks substituted; host is the URL one, ServerName may be an SNI override
+func inspectCert(target *checklist.Target, host string, state *tls.ConnectionState) bool {
+	chain := certcheck.Inspect(host, state.PeerCertificates)
+	target.CertSubject = chain.Subject
+	target.CertIssuer = chain.Issuer
+	target.CertSPKI = chain.SPKI
+	target.CertNotAfter = chain.NotAfter
+	if chain.Verdict == "" {
+		return false
+	}
+	target.LastResponseCode = 0
+	target.LastOutcome = checklist.OutcomeMITM
+	target.LastError = chain.Verdict
+	return true
+}
+
+// readVolume reads up to limit bytes and keeps the beginning for block-page matching
+func readVolume(r io.Reader, limit int64) ([]byte, int64, error) {
+	var head []byte
+	var n int64
+	buf := make([]byte, 32*1024)
+	for n < limit {
+		m, err := r.Read(buf[:min(int64(len(buf)), limit-n)])
+		if m > 0 && len(head) < blockpage.MaxBody {
+			head = append(head, buf[:min(m, blockpage.MaxBody-len(head))]...)
+		}
+		n += int64(m)
+		if err == io.EOF {
+			return head, n, nil
+		}
+		if err != nil {
+			return head, n, err
+		}
+	}
+	return head, n, nil
+}
+
+func classifyError(err error, stage int32, isQUIC bool) string {
+	if errors.Is(err, errBlockpage) {
+		return checklist.OutcomeBlockpage
+	}
+	if errors.Is(err, errRedirect) {
+		return checklist.OutcomeRedirect
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return checklist.OutcomeDNS
+	}
+
+	var certErr *tls.CertificateVerificationError
+	var hostnameErr x509.HostnameError
+	var authorityErr x509.UnknownAuthorityError
+	var invalidErr x509.CertificateInvalidError
+	if errors.As(err, &certErr) || errors.As(err, &hostnameErr) || errors.As(err, &authorityErr) || errors.As(err, &invalidErr) {
+		return checklist.OutcomeCertMismatch
+	}
+
+	var alertErr tls.AlertError
+	if errors.As(err, &alertErr) {
+		return checklist.OutcomeTLSAlert
+	}
+	var quicErr *quic.TransportError
+	if errors.As(err, &quicErr) && quicErr.ErrorCode.IsCryptoError() {
+		// quic-go reports local verification failures as crypto errors too
+		if strings.Contains(quicErr.ErrorMessage, "x509") || strings.Contains(quicErr.ErrorMessage, "certificate") {
+			return checklist.OutcomeCertMismatch
+		}
+		return checklist.OutcomeTLSAlert
+	}
+
+	timeout := errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded)
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		timeout = true
+	}
+	reset := errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, windows.WSAECONNRESET) || errors.Is(err, windows.WSAECONNREFUSED) || errors.Is(err, windows.WSAECONNABORTED)
+
+	switch {
+	case timeout && isQUIC && stage < stageHTTP:
+		return checklist.OutcomeQUICTimeout
+	case timeout && stage == stageConnect:
+		return checklist.OutcomeTCPTimeout
+	case timeout && stage == stageTLS:
+		return checklist.OutcomeTLSTimeout
+	case timeout:
+		return checklist.OutcomeHTTPTimeout
+	case reset:
+		return checklist.OutcomeTCPRST
+	}
+	return checklist.OutcomeOther
+}
@@ -0,0 +1,106 @@
+package requestsnative
+
+import (
+	"context"
+	"fmt"
+	"goodcheckgogo/checklist"
+	"goodcheckgogo/options"
+	"goodcheckgogo/strategy"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+// run with 'go test -race': routes and transports are shared by every request in flight
+func TestSendRequestConcurrentRoutes(t *testing.T) {
+	const sitesN = 300
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Host)
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	strategy.Protocol = "HTTP"
+	strategy.Proxy = "noproxy"
+	strategy.ProtoFull = "tcp4"
+	options.MyOptions.InternalTimeoutMs.Value = 10
+	SetTransport(sitesN, 5)
+
+	sites := make([]checklist.Website, sitesN)
+	for i := range sites {
+		sites[i] = checklist.NewWebsite(fmt.Sprintf("https://site%d.test:%s", i, u.Port()))
+		sites[i].PlainHTTP = true
+		sites[i].IPV = 4
+		checklist.SetTargets(&sites[i], []string{"127.0.0.1"}, 0)
+	}
+	err = SetRoutes(sites)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// not in routes, so it has to go by name
+	unrouted := checklist.NewWebsite(fmt.Sprintf("https://localhost:%s", u.Port()))
+	unrouted.PlainHTTP = true
+	unrouted.IPV = 4
+	checklist.SetTargets(&unrouted, []string{"127.0.0.1"}, 0)
+	sites = append(sites, unrouted)
+
+	var wg sync.WaitGroup
+	for i := range sites {
+		for j := range sites[i].Targets {
+			wg.Add(1)
+			go SendRequest(context.Background(), &wg, &sites[i], &sites[i].Targets[j])
+		}
+	}
+	wg.Wait()
+
+	for _, site := range sites {
+		for _, target := range site.Targets {
+			if target.LastOutcome != checklist.OutcomeSuccess || target.LastResponseCode != http.StatusOK {
+				t.Errorf("%s (%s): got %s %d: %s", site.Address, target.IP, target.LastOutcome, target.LastResponseCode, target.LastError)
+			}
+		}
+	}
+}
+
+func TestRouteAddr(t *testing.T) {
+	_routes = map[string][]string{
+		"a.test":     {"192.0.2.1", "192.0.2.2"},
+		"b.test":     {"192.0.2.3"},
+		"proxy.test": {""},
+	}
+	ctx := context.WithValue(context.Background(), targetKey{}, "192.0.2.2")
+
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{"a.test:443", "192.0.2.2:443"},
+		// redirect to another checklist host keeps its own route
+		{"b.test:443", "192.0.2.3:443"},
+		// hosts outside of the checklist go by name
+		{"unknown.test:443", "unknown.test:443"},
+		{"proxy.test:443", "proxy.test:443"},
+	}
+	for _, tt := range tests {
+		got, err := routeAddr(ctx, tt.addr)
+		if err != nil || got != tt.want {
+			t.Errorf("routeAddr(%s) = %s, %v; want %s", tt.addr, got, err, tt.want)
+		}
+	}
+
+	got, err := routeAddr(context.Background(), "a.test:80")
+	if err != nil || got != net.JoinHostPort("192.0.2.1", "80") {
+		t.Errorf("routeAddr without target = %s, %v; want the first route", got, err)
+	}
+	if _, err = routeAddr(ctx, "no-port.test"); err == nil {
+		t.Errorf("routeAddr without port: expected an error")
+	}
+}
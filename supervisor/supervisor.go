@@ -0,0 +1,226 @@
+package supervisor
+
+import (
+	"encoding/json"
+	"fmt"
+	"goodcheckgogo/utils"
+	"log"
+	"os"
+	"os/exec"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"time"
+)
+
+// everything the run has changed in the system; it's written to the marker file, so a crash can be cleaned up later
+type state struct {
+	PID       int                `json:"pid"`
+	Started   time.Time          `json:"started"`
+	Processes []string           `json:"processes"`
+	Services  []string           `json:"services"`
+	TempDirs  []string           `json:"temp_dirs"`
+	DNS       []utils.AdapterDNS `json:"dns"`
+}
+
+var (
+	mutex   sync.Mutex
+	marker  string
+	current state
+	running []*exec.Cmd
+)
+
+func Init(markerFile string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	marker = markerFile
+	current = state{
+		PID:     os.Getpid(),
+		Started: time.Now(),
+	}
+}
+
+// Recover cleans up after a previous run that never reached its teardown
+func Recover() error {
+	data, err := os.ReadFile(marker)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("can't read recovery marker '%s': %v", marker, err)
+	}
+	var previous state
+	err = json.Unmarshal(data, &previous)
+	if err != nil {
+		log.Printf("Recovery marker '%s' is damaged, removing it: %v\n", marker, err)
+		return os.Remove(marker)
+	}
+	if previous.PID != os.Getpid() {
+		alive, err := utils.PidExists(int32(previous.PID))
+		if err == nil && alive {
+			log.Printf("Recovery marker belongs to a running process (PID %d), leaving it alone\n", previous.PID)
+			return nil
+		}
+	}
+
+	log.Printf("Previous run started at %s didn't finish cleanly, cleaning up after it...\n", previous.Started.Format(time.DateTime))
+	// the marker goes away even if cleanup fails, otherwise every launch would stumble on the same error
+	if len(previous.Processes) > 0 {
+		err = utils.TaskKill(previous.Processes...)
+		if err != nil {
+			log.Printf("Can't terminate leftover processes: %v\n", err)
+		}
+	}
+	if len(previous.Services) > 0 {
+		err = utils.StopAndDeleteServices(previous.Services...)
+		if err != nil {
+			log.Printf("Can't stop leftover services: %v\n", err)
+		}
+	}
+	if len(previous.DNS) > 0 {
+		err = utils.RestoreAdapterDNS(previous.DNS)
+		if err != nil {
+			log.Printf("Can't restore DNS servers of adapters: %v\n", err)
+		}
+	}
+	removeTempDirs(previous.TempDirs)
+	err = os.Remove(marker)
+	if err != nil {
+		return fmt.Errorf("can't remove recovery marker '%s': %v", marker, err)
+	}
+	return nil
+}
+
+// Register makes cmd a part of the teardown; name is the image name for cleaning up after a crash
+func Register(name string, cmd *exec.Cmd) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+	running = append(running, cmd)
+	if !slices.Contains(current.Processes, name) {
+		current.Processes = append(current.Processes, name)
+	}
+	return save()
+}
+
+// Unregister is for processes that were stopped the normal way
+func Unregister(cmd *exec.Cmd) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	running = slices.DeleteFunc(running, func(c *exec.Cmd) bool {
+		return c == cmd
+	})
+}
+
+// RegisterServices records services the fooling program installs, such as WinDivert driver
+func RegisterServices(names ...string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+	for _, name := range names {
+		if !slices.Contains(current.Services, name) {
+			current.Services = append(current.Services, name)
+		}
+	}
+	return save()
+}
+
+// RegisterTempDir makes dir removed on teardown, or on the next launch if this one crashes
+func RegisterTempDir(dir string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if !slices.Contains(current.TempDirs, dir) {
+		current.TempDirs = append(current.TempDirs, dir)
+	}
+	return save()
+}
+
+// RegisterDNS must be called before adapters are changed, so a crash in between still restores them
+func RegisterDNS(adapters []utils.AdapterDNS) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+	current.DNS = adapters
+	return save()
+}
+
+// RestoreDNS puts adapters back the way they were; Teardown calls it as well
+func RestoreDNS() {
+	mutex.Lock()
+	defer mutex.Unlock()
+	restoreDNS()
+}
+
+func restoreDNS() {
+	if len(current.DNS) == 0 {
+		return
+	}
+	err := utils.RestoreAdapterDNS(current.DNS)
+	if err != nil {
+		log.Printf("Can't restore DNS servers of adapters: %v\n", err)
+	} else {
+		log.Printf("DNS servers of %d adapter(s) were restored\n", len(current.DNS))
+	}
+	current.DNS = nil
+}
+
+func removeTempDirs(dirs []string) {
+	for _, dir := range dirs {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			log.Printf("Can't remove temporary folder '%s': %v\n", dir, err)
+		}
+	}
+}
+
+func save() error {
+	data, err := json.MarshalIndent(current, "", "  ")
+	if err != nil {
+		return fmt.Errorf("can't encode recovery marker: %v", err)
+	}
+	err = os.WriteFile(marker, data, 0644)
+	if err != nil {
+		return fmt.Errorf("can't write recovery marker '%s': %v", marker, err)
+	}
+	return nil
+}
+
+// Teardown is safe to call more than once and from any exit path; errors are only logged, there is nothing else to do with them
+func Teardown() {
+	mutex.Lock()
+	defer mutex.Unlock()
+	for _, cmd := range running {
+		if cmd.Process == nil {
+			continue
+		}
+		err := cmd.Process.Kill()
+		if err != nil && err != os.ErrProcessDone {
+			log.Printf("Can't terminate process %d: %v\n", cmd.Process.Pid, err)
+		}
+	}
+	running = nil
+	if len(current.Services) > 0 {
+		err := utils.StopAndDeleteServices(current.Services...)
+		if err != nil {
+			log.Printf("Can't stop services: %v\n", err)
+		}
+	}
+	restoreDNS()
+	removeTempDirs(current.TempDirs)
+	current.Processes = nil
+	current.Services = nil
+	current.TempDirs = nil
+	err := os.Remove(marker)
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("Can't remove recovery marker '%s': %v\n", marker, err)
+	}
+}
+
+// Guard must be deferred at the top of every goroutine; a panic anywhere tears everything down before the crash
+func Guard() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	log.Printf("\nPanic: %v\n%s\n", r, debug.Stack())
+	Teardown()
+	log.Printf("\nExiting after a panic...\n")
+	os.Exit(2)
+}
@@ -0,0 +1,319 @@
+package tlsprofile
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"goodcheckgogo/options"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type Profile struct {
+	Name         string
+	MinVersion   uint16
+	MaxVersion   uint16
+	ALPN         []string
+	CipherSuites []uint16
+	Curves       []tls.CurveID
+	PadTo        int
+	SNI          string
+}
+
+// specs are 'key:value' pairs separated by ';', the same way custom profiles are written in config
+var builtins = map[string]string{
+	"default": "",
+	"tls12":   "min:1.2;max:1.2",
+	"tls13":   "min:1.3;max:1.3",
+	"minimal": "min:1.3;max:1.3;alpn:http/1.1;curves:X25519",
+	"browser": "min:1.2;max:1.3;alpn:h2,http/1.1;curves:X25519,P256,P384",
+	"large":   "alpn:http/1.1;pad:2000",
+}
+
+var curves = map[string]tls.CurveID{
+	"X25519": tls.X25519,
+	"P256":   tls.CurveP256,
+	"P384":   tls.CurveP384,
+	"P521":   tls.CurveP521,
+}
+
+// a hello past a single TLS record (16 KB) gets fragmented, which is a different test altogether
+const maxPad = 16000
+
+var versions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func Names() []string {
+	var names []string
+	for name := range builtins {
+		names = append(names, name)
+	}
+	for name := range options.MyOptions.TLSProfiles {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Lookup prefers profiles from config, so built-in ones can be redefined
+func Lookup(name string) (Profile, error) {
+	spec, ok := options.MyOptions.TLSProfiles[name]
+	if !ok {
+		spec, ok = builtins[name]
+	}
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown TLS profile '%s'; available: %s", name, strings.Join(Names(), ", "))
+	}
+	p, err := parse(name, spec)
+	if err != nil {
+		return Profile{}, fmt.Errorf("can't parse TLS profile '%s': %v", name, err)
+	}
+	return p, nil
+}
+
+func parse(name string, spec string) (Profile, error) {
+	p := Profile{
+		Name: name,
+	}
+	for _, pair := range strings.Split(spec, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, ":")
+		if !ok || value == "" {
+			return p, fmt.Errorf("expected 'key:value', got '%s'", pair)
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "min", "max":
+			v, ok := versions[strings.TrimSpace(value)]
+			if !ok {
+				return p, fmt.Errorf("unknown TLS version '%s'", value)
+			}
+			if strings.EqualFold(key, "min") {
+				p.MinVersion = v
+			} else {
+				p.MaxVersion = v
+			}
+		case "alpn":
+			p.ALPN = strings.Split(value, ",")
+		case "ciphers":
+			for _, c := range strings.Split(value, ",") {
+				id, err := cipherSuite(c)
+				if err != nil {
+					return p, err
+				}
+				p.CipherSuites = append(p.CipherSuites, id)
+			}
+		case "curves":
+			for _, c := range strings.Split(value, ",") {
+				id, ok := curves[strings.ToUpper(c)]
+				if !ok {
+					return p, fmt.Errorf("unknown curve '%s'", c)
+				}
+				p.Curves = append(p.Curves, id)
+			}
+		case "pad":
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || n < 0 || n > maxPad {
+				return p, fmt.Errorf("padding must be a number from 0 to %d, got '%s'", maxPad, value)
+			}
+			p.PadTo = n
+		case "sni":
+			p.SNI = value
+		default:
+			return p, fmt.Errorf("unknown key '%s'", key)
+		}
+	}
+	if p.MinVersion != 0 && p.MaxVersion != 0 && p.MinVersion > p.MaxVersion {
+		return p, fmt.Errorf("minimal version is greater than maximal")
+	}
+	return p, nil
+}
+
+func cipherSuite(name string) (uint16, error) {
+	for _, c := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		if strings.EqualFold(c.Name, name) {
+			return c.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown cipher suite '%s'", name)
+}
+
+// Config applies profile on top of base; ALPN is left to the caller for QUIC, where it must be 'h3'
+func (p Profile) Config(base *tls.Config, withALPN bool) *tls.Config {
+	c := base.Clone()
+	if p.MinVersion != 0 {
+		c.MinVersion = p.MinVersion
+	}
+	if p.MaxVersion != 0 {
+		c.MaxVersion = p.MaxVersion
+	}
+	if len(p.CipherSuites) > 0 {
+		c.CipherSuites = p.CipherSuites
+	}
+	if len(p.Curves) > 0 {
+		c.CurvePreferences = p.Curves
+	}
+	if p.SNI != "" {
+		// crypto/tls verifies against ServerName too, ForHost moves that back to the URL host
+		c.ServerName = p.SNI
+	}
+	if withALPN {
+		c.NextProtos = slices.Clone(p.ALPN)
+		if p.PadTo > 0 {
+			// padding entries are never chosen, without a real protocol in front servers would reject the hello
+			if len(c.NextProtos) == 0 {
+				c.NextProtos = []string{"http/1.1"}
+			}
+			c.NextProtos = append(c.NextProtos, padding(p.PadTo, c.NextProtos)...)
+		}
+	}
+	return c
+}
+
+// ForHost keeps SNI override on the wire, but checks the certificate against host, the one in the URL
+func ForHost(c *tls.Config, host string) *tls.Config {
+	if c.InsecureSkipVerify || c.ServerName == "" || strings.EqualFold(c.ServerName, host) {
+		return c
+	}
+	c = c.Clone()
+	roots := c.RootCAs
+	c.InsecureSkipVerify = true
+	c.VerifyConnection = func(state tls.ConnectionState) error {
+		if len(state.PeerCertificates) == 0 {
+			return fmt.Errorf("server sent no certificate")
+		}
+		intermediates := x509.NewCertPool()
+		for _, cert := range state.PeerCertificates[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+			DNSName:       host,
+			Roots:         roots,
+			Intermediates: intermediates,
+		})
+		if err != nil {
+			return &tls.CertificateVerificationError{UnverifiedCertificates: state.PeerCertificates, Err: err}
+		}
+		return nil
+	}
+	return c
+}
+
+func (p Profile) HasALPN(proto string) bool {
+	return slices.Contains(p.ALPN, proto)
+}
+
+// crypto/tls has no padding extension (RFC 7685), so ClientHello is inflated with dummy ALPN entries
+// servers skip protocols they don't know; the size is approximate since SNI length varies
+func padding(padTo int, protos []string) []string {
+	const estimatedHello = 320
+	var entries []string
+	left := padTo - estimatedHello
+	for _, proto := range protos {
+		left -= len(proto) + 1
+	}
+	for n := 0; left > 1; n++ {
+		size := min(left-1, 255)
+		entry := fmt.Sprintf("pad%d-", n)
+		if size > len(entry) {
+			entry += strings.Repeat("x", size-len(entry))
+		}
+		entries = append(entries, entry)
+		left -= len(entry) + 1
+	}
+	return entries
+}
+
+func (p Profile) Describe() string {
+	var parts []string
+	if p.MinVersion != 0 {
+		parts = append(parts, "min "+tls.VersionName(p.MinVersion))
+	}
+	if p.MaxVersion != 0 {
+		parts = append(parts, "max "+tls.VersionName(p.MaxVersion))
+	}
+	if len(p.ALPN) > 0 {
+		parts = append(parts, "ALPN "+strings.Join(p.ALPN, ","))
+	}
+	if len(p.CipherSuites) > 0 {
+		parts = append(parts, fmt.Sprintf("%d cipher suites", len(p.CipherSuites)))
+	}
+	if len(p.Curves) > 0 {
+		var names []string
+		for _, c := range p.Curves {
+			names = append(names, c.String())
+		}
+		parts = append(parts, "curves "+strings.Join(names, ","))
+	}
+	if p.PadTo > 0 {
+		parts = append(parts, fmt.Sprintf("padded to ~%d bytes with dummy ALPN", p.PadTo))
+	}
+	if p.SNI != "" {
+		parts = append(parts, "SNI "+p.SNI)
+	}
+	if len(parts) == 0 {
+		return p.Name + " (Go defaults)"
+	}
+	return fmt.Sprintf("%s (%s)", p.Name, strings.Join(parts, ", "))
+}
+
+// CurlKeys maps what curl can express, every option with its parameter; cipher suites, padding and SNI override have no equivalent there
+func (p Profile) CurlKeys(isQUIC bool) ([][]string, []string) {
+	var keys [][]string
+	var skipped []string
+	switch p.MinVersion {
+	case tls.VersionTLS10:
+		keys = append(keys, []string{"--tlsv1.0"})
+	case tls.VersionTLS11:
+		keys = append(keys, []string{"--tlsv1.1"})
+	case tls.VersionTLS12:
+		keys = append(keys, []string{"--tlsv1.2"})
+	case tls.VersionTLS13:
+		keys = append(keys, []string{"--tlsv1.3"})
+	}
+	if p.MaxVersion != 0 {
+		keys = append(keys, []string{"--tls-max", strings.TrimPrefix(tls.VersionName(p.MaxVersion), "TLS ")})
+	}
+	if len(p.Curves) > 0 {
+		var names []string
+		for _, c := range p.Curves {
+			switch c {
+			case tls.X25519:
+				names = append(names, "X25519")
+			case tls.CurveP256:
+				names = append(names, "P-256")
+			case tls.CurveP384:
+				names = append(names, "P-384")
+			case tls.CurveP521:
+				names = append(names, "P-521")
+			}
+		}
+		keys = append(keys, []string{"--curves", strings.Join(names, ":")})
+	}
+	if len(p.ALPN) > 0 && !isQUIC {
+		if p.HasALPN("h2") {
+			keys = append(keys, []string{"--http2"})
+		} else {
+			keys = append(keys, []string{"--http1.1"})
+		}
+	}
+	if len(p.CipherSuites) > 0 {
+		skipped = append(skipped, "cipher suites")
+	}
+	if p.PadTo > 0 {
+		// curl sends its own hello, so the padded size isn't reproduced at all
+		skipped = append(skipped, "padding")
+	}
+	if p.SNI != "" {
+		skipped = append(skipped, "SNI override")
+	}
+	return keys, skipped
+}